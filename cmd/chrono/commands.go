@@ -0,0 +1,862 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/export"
+	"github.com/mellonnen/chronograph/git"
+	"github.com/mellonnen/chronograph/hooks"
+	"github.com/mellonnen/chronograph/importer"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/report"
+	"github.com/mellonnen/chronograph/server"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/tracker"
+)
+
+// command is a non-interactive subcommand of chrono.
+type command func(s store.Store, cfg config.Config, args []string, w io.Writer) error
+
+var commands = map[string]command{
+	"start":  startCommand,
+	"stop":   stopCommand,
+	"status": statusCommand,
+	"ls":     lsCommand,
+	"add":    addCommand,
+	"report": reportCommand,
+	"export": exportCommand,
+	"import": importCommand,
+	"hooks":  hooksCommand,
+	"serve":  serveCommand,
+	"prompt": promptCommand,
+}
+
+// run executes the subcommand named by the first argument.
+func run(cfg config.Config, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, see chrono -h", args[0])
+	}
+	open := store.Open
+	// the prompt is drawn before every shell prompt, so it skips the migrations.
+	if args[0] == "prompt" {
+		open = store.OpenReadOnly
+	}
+	s, err := open(cfg.DatabasePath)
+	// before anything was tracked there is no database, and no running task
+	// for the prompt to show.
+	if args[0] == "prompt" && errors.Is(err, os.ErrNotExist) {
+		s, err = store.NewMemory(), nil
+	}
+	if err != nil {
+		return err
+	}
+	return cmd(s, cfg, args[1:], os.Stdout)
+}
+
+// parseArgs parses flags that may be interleaved with positional arguments,
+// and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func startCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	branch := fs.Bool("branch", false, "check out the branch of the task without asking")
+	noBranch := fs.Bool("no-branch", false, "start the task on the current branch")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("usage: chrono start [-branch|-no-branch] <task>")
+	}
+	if *branch && *noBranch {
+		return errors.New("-branch and -no-branch are mutually exclusive")
+	}
+	task, err := s.TaskByName(args[0])
+	if err != nil {
+		return err
+	}
+	repo, err := s.Repo(task.RepoID)
+	if err != nil {
+		return err
+	}
+	// Checked before asking about the branch, so that a task that cannot be
+	// started is not offered one.
+	switch {
+	case task.CompletedAt.Valid:
+		return fmt.Errorf("starting %s: %w", task.Name, tracker.ErrCompleted)
+	case task.Running():
+		return fmt.Errorf("starting %s: %w", task.Name, tracker.ErrRunning)
+	}
+	var checkout string
+	if cfg.BranchTemplate != "" && !*noBranch {
+		if checkout, err = branchToCheckOut(cfg.BranchTemplate, task, repo.Path, *branch, w); err != nil {
+			return err
+		}
+	}
+
+	// Only one timer runs at a time, the others are only paused if the task starts.
+	_, paused, err := tracker.Switch(s, task, repo.Path, checkout)
+	if err != nil {
+		return err
+	}
+	if checkout != "" {
+		fmt.Fprintf(w, "Checked out %s\n", checkout)
+	}
+	for _, t := range paused {
+		fmt.Fprintf(w, "Paused %s\n", t.Name)
+	}
+	fmt.Fprintf(w, "Started %s\n", task.Name)
+	return nil
+}
+
+// branchToCheckOut returns the branch of the task if it should be checked out,
+// or "" if the repo already is on it. The user is asked first when the branch
+// is not forced and stdin is a terminal, otherwise the task is started on the
+// current branch.
+func branchToCheckOut(tmpl string, task models.Task, repoPath string, force bool, w io.Writer) (string, error) {
+	branch := task.Branch
+	if branch == "" {
+		var err error
+		if branch, err = tracker.BranchName(tmpl, task); err != nil {
+			return "", err
+		}
+	}
+	if repoPath == "" {
+		return "", nil
+	}
+	current, err := git.CurrentBranch(repoPath)
+	if err != nil || current == branch {
+		return "", nil
+	}
+	if !force {
+		if !isTerminal(os.Stdin) {
+			return "", nil
+		}
+		fmt.Fprintf(w, "Check out %s before starting %s? [y/N] ", branch, task.Name)
+		var answer string
+		fmt.Fscanln(os.Stdin, &answer)
+		if a := strings.ToLower(answer); a != "y" && a != "yes" {
+			return "", nil
+		}
+	}
+	return branch, nil
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func stopCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("stop", flag.ContinueOnError)
+	complete := fs.Bool("complete", false, "mark the task as complete")
+	atFlag := fs.String("at", "", "stop at an earlier time, HH:MM or YYYY-MM-DD HH:MM")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	at := time.Now()
+	if *atFlag != "" {
+		if at, err = parseStopTime(*atFlag, at); err != nil {
+			return err
+		}
+	}
+
+	var tasks []models.Task
+	switch len(args) {
+	case 0:
+		if tasks, err = s.RunningTasks(); err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return errors.New("no task is running")
+		}
+	case 1:
+		task, err := s.TaskByName(args[0])
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+	default:
+		return errors.New("usage: chrono stop [-complete] [-at time] [task]")
+	}
+
+	for _, t := range tasks {
+		if err := pause(s, t, *complete, at); err != nil {
+			return err
+		}
+		if *complete {
+			fmt.Fprintf(w, "Completed %s\n", t.Name)
+		} else {
+			fmt.Fprintf(w, "Paused %s\n", t.Name)
+		}
+	}
+	return nil
+}
+
+// pause pauses or completes a task at the given time.
+func pause(s store.Store, task models.Task, complete bool, at time.Time) error {
+	repo, err := s.Repo(task.RepoID)
+	if err != nil {
+		return err
+	}
+	if complete {
+		_, err = tracker.CompleteAt(s, task, repo.Path, at)
+	} else {
+		_, err = tracker.PauseAt(s, task, repo.Path, at)
+	}
+	return err
+}
+
+// parseStopTime parses the time given to stop -at. A time of day without a
+// date is the last time the clock showed it, which is yesterday if it has not
+// yet passed today.
+func parseStopTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("15:04", value, time.Local); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+		if at.After(now) {
+			at = at.AddDate(0, 0, -1)
+		}
+		return at, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected HH:MM or YYYY-MM-DD HH:MM", value)
+}
+
+func statusCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	running, err := s.RunningTasks()
+	if err != nil {
+		return err
+	}
+	if len(running) == 0 {
+		fmt.Fprintln(w, "No task is running")
+		return nil
+	}
+	now := time.Now()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, t := range running {
+		fmt.Fprintf(tw, "%s\t%s / %s\tsince %s\n",
+			t.Name,
+			models.ShortDuration(t.Tracked(now).Truncate(time.Second)),
+			models.ShortDuration(t.ExpectedDuration),
+			t.ActiveEntry().StartedAt.Format("15:04"),
+		)
+	}
+	return tw.Flush()
+}
+
+// promptTask is a running task as chrono prompt shows it, the format refers to
+// its fields, e.g. {{.Task}} {{.Elapsed}}/{{.Estimate}}.
+type promptTask struct {
+	Task   string `json:"task"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch,omitempty"`
+	// Elapsed is the time tracked on the task in total, Session only that of
+	// the running session.
+	Elapsed   promptDuration `json:"elapsed_seconds"`
+	Session   promptDuration `json:"session_seconds"`
+	Estimate  promptDuration `json:"estimate_seconds"`
+	Remaining promptDuration `json:"remaining_seconds"`
+	// Over is set when more time was tracked than estimated.
+	Over      bool      `json:"over"`
+	Pomodoro  bool      `json:"pomodoro"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// promptDuration is written in minutes in the prompt and in seconds in JSON.
+type promptDuration time.Duration
+
+func (d promptDuration) String() string {
+	return models.ShortDuration(time.Duration(d).Truncate(time.Minute))
+}
+
+func (d promptDuration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(time.Duration(d).Seconds()), 10)), nil
+}
+
+func promptCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("prompt", flag.ContinueOnError)
+	format := fs.String("format", cfg.PromptFormat, "template of each running task")
+	asJSON := fs.Bool("json", false, "write the running tasks as JSON")
+	sep := fs.String("sep", " | ", "separator between running tasks")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("usage: chrono prompt [-format template] [-sep separator] [-json]")
+	}
+	tmpl, err := template.New("prompt").Parse(*format)
+	if err != nil {
+		return fmt.Errorf("parsing prompt format: %w", err)
+	}
+
+	running, err := s.RunningTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tasks := make([]promptTask, 0, len(running))
+	for _, t := range running {
+		repo, err := s.Repo(t.RepoID)
+		if err != nil {
+			return err
+		}
+		entry := t.ActiveEntry()
+		elapsed := t.Tracked(now)
+		tasks = append(tasks, promptTask{
+			Task:      t.Name,
+			Repo:      repo.Name,
+			Branch:    t.Branch,
+			Elapsed:   promptDuration(elapsed),
+			Session:   promptDuration(entry.Duration(now)),
+			Estimate:  promptDuration(t.ExpectedDuration),
+			Remaining: promptDuration(t.ExpectedDuration - elapsed),
+			Over:      elapsed > t.ExpectedDuration,
+			Pomodoro:  entry.Pomodoro,
+			StartedAt: entry.StartedAt,
+		})
+	}
+
+	if *asJSON {
+		return json.NewEncoder(w).Encode(tasks)
+	}
+	// nothing is written when no task is running, so that the prompt stays empty.
+	if len(tasks) == 0 {
+		return nil
+	}
+	lines := make([]string, len(tasks))
+	for i, t := range tasks {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, t); err != nil {
+			return fmt.Errorf("formatting prompt: %w", err)
+		}
+		lines[i] = b.String()
+	}
+	_, err = fmt.Fprintln(w, strings.Join(lines, *sep))
+	return err
+}
+
+func lsCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: chrono ls workspaces|repos|tasks")
+	}
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	workspaceName := fs.String("workspace", "", "only list repos of this workspace")
+	repoName := fs.String("repo", "", "only list tasks of this repo")
+	tag := fs.String("tag", "", "only list tasks with this tag")
+	if _, err := parseArgs(fs, args[1:]); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	switch args[0] {
+	case "workspaces":
+		workspaces, err := s.Workspaces()
+		if err != nil {
+			return err
+		}
+		for _, ws := range workspaces {
+			fmt.Fprintf(tw, "%s\t%s\n", ws.Name, ws.GetDescription())
+		}
+
+	case "repos":
+		var workspaceID uint
+		if *workspaceName != "" {
+			workspace, err := s.WorkspaceByName(*workspaceName)
+			if err != nil {
+				return err
+			}
+			workspaceID = workspace.ID
+		}
+		repos, err := s.Repos(workspaceID)
+		if err != nil {
+			return err
+		}
+		for _, r := range repos {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Name, r.Path, r.GetDescription())
+		}
+
+	case "tasks":
+		var repoID uint
+		if *repoName != "" {
+			repo, err := s.RepoByName(*repoName)
+			if err != nil {
+				return err
+			}
+			repoID = repo.ID
+		}
+		tasks, err := s.Tasks(repoID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, t := range tasks {
+			if *tag != "" && !t.HasTag(*tag) {
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s / %s\t%s\n",
+				t.Name,
+				t.Status(),
+				models.ShortDuration(t.Tracked(now).Truncate(time.Second)),
+				models.ShortDuration(t.ExpectedDuration),
+				strings.Join(t.TagNames(), ", "),
+			)
+		}
+
+	default:
+		return fmt.Errorf("cannot list %q, expected workspaces, repos or tasks", args[0])
+	}
+	return tw.Flush()
+}
+
+func addCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: chrono add workspace|repo|task <name>")
+	}
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	description := fs.String("description", "", "description of the resource")
+	workspaceName := fs.String("workspace", "", "workspace to add the repo to")
+	repoName := fs.String("repo", "", "repo to add the task to")
+	path := fs.String("path", ".", "path to the repo")
+	estimate := fs.Duration("estimate", 0, "estimated time of the task")
+	tags := fs.String("tags", "", "comma separated tags of the task")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: chrono add %s <name>", args[0])
+	}
+	name := rest[0]
+	desc := sql.NullString{String: *description, Valid: len(*description) > 0}
+
+	switch args[0] {
+	case "workspace":
+		workspace := models.Workspace{Name: name, Description: desc}
+		if err := s.CreateWorkspace(&workspace); err != nil {
+			return err
+		}
+
+	case "repo":
+		if *workspaceName == "" {
+			return errors.New("a repo needs a -workspace")
+		}
+		workspace, err := s.WorkspaceByName(*workspaceName)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(*path)
+		if err != nil {
+			return err
+		}
+		repoPath, err := git.RepoPathFromPath(abs)
+		if err != nil {
+			return err
+		}
+		remote, err := git.RemoteFromPath(repoPath)
+		if err != nil {
+			return fmt.Errorf("getting remote: %w", err)
+		}
+		repo := models.Repo{WorkspaceID: workspace.ID, Name: name, Description: desc, Path: repoPath, Remote: remote}
+		if err := s.CreateRepo(&repo); err != nil {
+			return err
+		}
+
+	case "task":
+		if *repoName == "" {
+			return errors.New("a task needs a -repo")
+		}
+		repo, err := s.RepoByName(*repoName)
+		if err != nil {
+			return err
+		}
+		task := models.Task{RepoID: repo.ID, Name: name, Description: desc, ExpectedDuration: *estimate}
+		err = s.Transaction(func(s store.Store) error {
+			if err := s.CreateTask(&task); err != nil {
+				return err
+			}
+			task.Tags = models.ParseTags(*tags)
+			return s.SetTaskTags(&task)
+		})
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("cannot add %q, expected workspace, repo or task", args[0])
+	}
+	fmt.Fprintf(w, "Added %s %s\n", args[0], name)
+	return nil
+}
+
+func reportCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	archived := fs.Bool("archived", false, "include archived workspaces, repos and tasks")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := s.Workspaces()
+	if err != nil {
+		return err
+	}
+	if *archived {
+		archivedWorkspaces, err := s.ArchivedWorkspaces()
+		if err != nil {
+			return err
+		}
+		workspaces = append(workspaces, archivedWorkspaces...)
+	}
+	switch len(args) {
+	case 0:
+	case 1:
+		var named []models.Workspace
+		for _, ws := range workspaces {
+			if ws.Name == args[0] {
+				named = append(named, ws)
+			}
+		}
+		if len(named) == 0 {
+			return fmt.Errorf("fetching workspace %q: %w", args[0], store.ErrNotFound)
+		}
+		workspaces = named
+	default:
+		return errors.New("usage: chrono report [-archived] [workspace]")
+	}
+
+	now := time.Now()
+	for i, ws := range workspaces {
+		ws, err := tracker.WorkspaceTree(s, ws, *archived)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := writeReport(w, ws.Name, report.New(ws, now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "csv, json or ics, defaults to the extension of -o or csv")
+	out := fs.String("o", "", "file to write to instead of stdout")
+	from := fs.String("from", "", "only export sessions from this date, YYYY-MM-DD")
+	to := fs.String("to", "", "only export sessions up to and including this date, YYYY-MM-DD")
+	workspace := fs.String("workspace", "", "only export this workspace")
+	repo := fs.String("repo", "", "only export this repo")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("usage: chrono export [-format csv|json|ics] [-o file] [-from date] [-to date] [-workspace name] [-repo name]")
+	}
+
+	f := export.CSV
+	switch {
+	case *format != "":
+		f, err = export.ParseFormat(*format)
+	case *out != "":
+		f, err = export.FormatFromPath(*out)
+	}
+	if err != nil {
+		return err
+	}
+	filter := export.Filter{Workspace: *workspace, Repo: *repo}
+	if filter.From, filter.To, err = export.ParseRange(*from, *to); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	workspaces, err := export.Collect(s, filter, now)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return export.Write(w, f, workspaces, now)
+	}
+	return export.WriteFile(config.ExpandPath(*out), f, workspaces, now)
+}
+
+func importCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print what would be created without saving it")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errors.New("usage: chrono import [-dry-run] timewarrior|toggl <file>")
+	}
+	parse, ok := importer.Parsers[args[0]]
+	if !ok {
+		return fmt.Errorf("cannot import from %q, expected timewarrior or toggl", args[0])
+	}
+
+	in := os.Stdin
+	if args[1] != "-" {
+		if in, err = os.Open(config.ExpandPath(args[1])); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	records, err := parse(in)
+	if err != nil {
+		return err
+	}
+	res, err := importer.Import(s, args[0], records, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "Imported"
+	if *dryRun {
+		verb = "Would import"
+		for _, c := range res.Created {
+			fmt.Fprintf(w, "create %s\n", c)
+		}
+	}
+	fmt.Fprintf(w, "%s %d sessions into %d new workspaces, %d new repos and %d new tasks, skipped %d already imported sessions\n",
+		verb, res.Sessions, res.Workspaces, res.Repos, res.Tasks, res.Duplicates)
+	return nil
+}
+
+func hooksCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	const usage = "usage: chrono hooks install [-force] [repo] | uninstall [repo]"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	// git runs these from the top of the working tree.
+	case "prepare-commit-msg":
+		if len(args) < 2 {
+			return errors.New("usage: chrono hooks prepare-commit-msg <file> [source] [sha]")
+		}
+		return hooks.PrepareCommitMsg(s, cwd, args[1])
+	case "post-commit":
+		tasks, err := hooks.PostCommit(s, cwd)
+		for _, t := range tasks {
+			fmt.Fprintf(w, "chrono: linked commit to %s\n", t.Name)
+		}
+		return err
+	}
+
+	fs := flag.NewFlagSet("hooks "+args[0], flag.ContinueOnError)
+	force := fs.Bool("force", false, "back up and replace existing hooks")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	var repo models.Repo
+	switch len(rest) {
+	case 0:
+		repo, err = hooks.RepoAt(s, cwd)
+	case 1:
+		repo, err = s.RepoByName(rest[0])
+	default:
+		return errors.New(usage)
+	}
+	if err != nil {
+		return err
+	}
+	if repo.Path == "" {
+		return fmt.Errorf("%s: %w", repo.Name, tracker.ErrNoPath)
+	}
+
+	var paths []string
+	switch args[0] {
+	case "install":
+		command, err := hookCommand(cfg)
+		if err != nil {
+			return err
+		}
+		paths, err = hooks.Install(repo.Path, command, *force)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Installed hooks in %s:\n", repo.Name)
+	case "uninstall":
+		paths, err = hooks.Uninstall(repo.Path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed hooks from %s:\n", repo.Name)
+	default:
+		return errors.New(usage)
+	}
+	for _, p := range paths {
+		fmt.Fprintf(w, "  %s\n", p)
+	}
+	return nil
+}
+
+// hookCommand returns how the hooks run this binary, with absolute paths to
+// the config and the database that are in use now.
+func hookCommand(cfg config.Config) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	configPath, err := filepath.Abs(config.ExpandPath(cfg.File))
+	if err != nil {
+		return nil, err
+	}
+	dbPath, err := filepath.Abs(cfg.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+	return []string{executable, "-config", configPath, "-db", dbPath}, nil
+}
+
+func serveCommand(s store.Store, cfg config.Config, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", server.DefaultAddr, "host and port to listen on")
+	socket := fs.String("socket", "", "path of a unix socket to listen on instead of -addr")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("usage: chrono serve [-addr host:port | -socket path]")
+	}
+
+	var l net.Listener
+	if *socket != "" {
+		l, err = listenUnix(config.ExpandPath(*socket))
+	} else {
+		l, err = net.Listen("tcp", *addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Handler: server.New(s)}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(w, "Serving the API on %s\n", l.Addr())
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// listenUnix listens on a unix socket, replacing the socket of a server that
+// did not shut down cleanly.
+func listenUnix(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another server is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// writeReport writes a report as plain text tables.
+func writeReport(w io.Writer, name string, r report.Report) error {
+	fmt.Fprintf(w, "%s\n%s\n\n", name, strings.Repeat("=", len(name)))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tREPO\tESTIMATE\tACTUAL\tDELTA\tRATIO\tPOMODOROS\tCYCLE TIME")
+	for _, row := range r.Rows {
+		task := row.Task
+		if !row.Complete {
+			task += " (in progress)"
+		}
+		if row.Archived {
+			task += " (archived)"
+		}
+		cycle := "-"
+		if row.Complete {
+			cycle = models.ShortDuration(row.CycleTime.Truncate(time.Minute))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.2f\t%d\t%s\n",
+			task,
+			row.Repo,
+			models.ShortDuration(row.Estimate),
+			models.ShortDuration(row.Actual.Truncate(time.Minute)),
+			models.SignedDuration(row.Delta().Truncate(time.Minute)),
+			row.Ratio(),
+			row.Pomodoros,
+			cycle,
+		)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "COMPLETED\tTASKS\tESTIMATE\tACTUAL\tRATIO\tMEAN OVERRUN\tWITHIN ESTIMATE")
+	for _, a := range append(r.Repos, r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.2f\t%s\t%.0f%%\n",
+			a.Name,
+			a.Tasks,
+			models.ShortDuration(a.Estimate),
+			models.ShortDuration(a.Actual.Truncate(time.Minute)),
+			a.Ratio(),
+			models.SignedDuration(a.MeanOverrun().Truncate(time.Minute)),
+			a.WithinShare()*100,
+		)
+	}
+	if len(r.Tags) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "TAG\tTASKS\tACTUAL")
+		for _, t := range r.Tags {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", t.Name, t.Tasks, models.ShortDuration(t.Actual.Truncate(time.Minute)))
+		}
+	}
+	return tw.Flush()
+}
@@ -1,15 +1,95 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"os"
 
+	"github.com/mellonnen/chronograph/config"
 	"github.com/mellonnen/chronograph/ui"
 )
 
 func main() {
-	program := ui.New("chronograph.db")
-	if err := program.Start(); err != nil {
-		log.Fatal(fmt.Errorf("initializing UI: %w", err))
+	configPath := flag.String("config", config.Path(), "path to the config file")
+	dbPath := flag.String("db", "", "path to the sqlite database, overrides the config")
+	theme := flag.String("theme", "", "theme of the TUI (auto, dark, light or plain), overrides the config")
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath, *dbPath, *theme)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chrono: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Without a subcommand we launch the TUI.
+	if flag.NArg() == 0 {
+		program := ui.New(cfg)
+		if err := program.Start(); err != nil {
+			log.Fatal(fmt.Errorf("initializing UI: %w", err))
+		}
+		return
+	}
+
+	if err := run(cfg, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "chrono: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadConfig loads the config file and applies the flag overrides.
+func loadConfig(path, dbPath, theme string) (config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return cfg, err
+	}
+	if dbPath != "" {
+		cfg.DatabasePath = dbPath
 	}
+	if theme != "" {
+		cfg.Theme = theme
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	cfg.DatabasePath = config.ExpandPath(cfg.DatabasePath)
+	return cfg, cfg.EnsureDatabaseDir()
+}
+
+func usage() {
+	fmt.Fprint(flag.CommandLine.Output(), `Usage: chrono [flags] [command]
+
+Running chrono without a command opens the TUI.
+
+Commands:
+  start [-branch|-no-branch] <task>  start or resume the timer of a task, offering
+                                     to check out its branch
+  stop [-complete] [-at time] [task] pause the running timers, or only the one of task,
+                                     at an earlier time such as 17:30 if given
+  status                             show the running tasks
+  prompt [-format template] [-json]  print the running tasks for a shell prompt or
+                                     a status bar, e.g. {{.Task}} {{.Elapsed}}/{{.Estimate}}
+  ls workspaces|repos|tasks          list resources, tasks can be narrowed down
+                                     with -tag
+  add workspace|repo|task <name>     add a resource, tasks can be given -tags
+  report [-archived] [workspace]     show the estimate-vs-actual report
+  export [-format csv|json|ics]      export sessions, see chrono export -h
+  import [-dry-run] timewarrior|toggl <file>
+                                     import sessions from another time tracker
+  hooks install [-force] [repo]      link commits to the running task with git hooks
+  hooks uninstall [repo]             remove the git hooks
+  serve [-addr host:port | -socket path]
+                                     serve a JSON API for editor plugins and
+                                     status bars
+
+The database location, theme, default estimate, board columns and key
+bindings are read from the config file. The environment variables
+CHRONOGRAPH_CONFIG, CHRONOGRAPH_DB, CHRONOGRAPH_THEME and
+CHRONOGRAPH_DEFAULT_ESTIMATE override the config file, and flags override
+both.
+
+Flags:
+`)
+	flag.PrintDefaults()
 }
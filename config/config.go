@@ -0,0 +1,354 @@
+// Package config loads the settings of chronograph.
+//
+// Settings are resolved in increasing order of precedence from the defaults,
+// the config file, environment variables and command line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Environment variables that override the config file.
+const (
+	EnvConfig          = "CHRONOGRAPH_CONFIG"
+	EnvDatabase        = "CHRONOGRAPH_DB"
+	EnvTheme           = "CHRONOGRAPH_THEME"
+	EnvDefaultEstimate = "CHRONOGRAPH_DEFAULT_ESTIMATE"
+)
+
+// Themes that the TUI can be rendered with.
+const (
+	ThemeAuto  = "auto"
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+	ThemePlain = "plain"
+)
+
+// Config holds all settings of chronograph.
+type Config struct {
+	// File is the config file that was loaded, which need not exist.
+	File            string        `toml:"-"`
+	DatabasePath    string        `toml:"database_path"`
+	Theme           string        `toml:"theme"`
+	DefaultEstimate time.Duration `toml:"default_estimate"`
+	// BranchTemplate names the git branch of a task, e.g. "task/{{.Name}}".
+	// Branches are not offered when it is empty.
+	BranchTemplate string `toml:"branch_template"`
+	// Sessions are inferred from commits that are at most InferGap apart, and
+	// start InferLeadIn before their first commit.
+	InferGap    time.Duration `toml:"infer_gap"`
+	InferLeadIn time.Duration `toml:"infer_lead_in"`
+	// StatsWeeks is how many weeks of daily hours the statistics show.
+	StatsWeeks int `toml:"stats_weeks"`
+	// PromptFormat is the template of chrono prompt, which is executed for
+	// every running task.
+	PromptFormat string `toml:"prompt_format"`
+	// IdleTimeout is how long the TUI waits without key presses or changes to
+	// the files of the repo before it asks what to do with a running timer.
+	// Zero turns idle detection off.
+	IdleTimeout time.Duration `toml:"idle_timeout"`
+	// Columns are the states of the task workflow, in the order the board
+	// shows them. New tasks start in the first column and tasks in the last
+	// column are completed.
+	Columns  []string `toml:"columns"`
+	Pomodoro Pomodoro `toml:"pomodoro"`
+	Keys     Keys     `toml:"keys"`
+}
+
+// Pomodoro holds the lengths of pomodoros and the breaks between them.
+type Pomodoro struct {
+	Work       time.Duration `toml:"work"`
+	ShortBreak time.Duration `toml:"short_break"`
+	LongBreak  time.Duration `toml:"long_break"`
+	// LongBreakEvery is the number of pomodoros after which the break is long.
+	LongBreakEvery int `toml:"long_break_every"`
+}
+
+// Break returns the length of the break after the nth finished pomodoro.
+func (p Pomodoro) Break(n int) time.Duration {
+	if n > 0 && n%p.LongBreakEvery == 0 {
+		return p.LongBreak
+	}
+	return p.ShortBreak
+}
+
+// Keys maps the actions of the TUI to the keys that trigger them.
+type Keys struct {
+	Add       []string `toml:"add"`
+	Choose    []string `toml:"choose"`
+	Remove    []string `toml:"remove"`
+	Edit      []string `toml:"edit"`
+	Start     []string `toml:"start"`
+	Pause     []string `toml:"pause"`
+	Complete  []string `toml:"complete"`
+	Annotate  []string `toml:"annotate"`
+	Report    []string `toml:"report"`
+	Archived  []string `toml:"archived"`
+	Restore   []string `toml:"restore"`
+	Export    []string `toml:"export"`
+	Infer     []string `toml:"infer"`
+	Timesheet []string `toml:"timesheet"`
+	Stats     []string `toml:"stats"`
+	Pomodoro  []string `toml:"pomodoro"`
+	Board     []string `toml:"board"`
+	Help      []string `toml:"help"`
+
+	// The keys below move around the timesheet and the board.
+	Up    []string `toml:"up"`
+	Down  []string `toml:"down"`
+	Left  []string `toml:"left"`
+	Right []string `toml:"right"`
+	Back  []string `toml:"back"`
+	// MoveLeft and MoveRight move the task under the cursor to the previous or
+	// next column of the board.
+	MoveLeft  []string `toml:"move_left"`
+	MoveRight []string `toml:"move_right"`
+	// Previous and Next page the timesheet by day or week, Today returns to
+	// the current one and View switches between the two.
+	Previous []string `toml:"previous"`
+	Next     []string `toml:"next"`
+	Today    []string `toml:"today"`
+	View     []string `toml:"view"`
+	// More and Fewer change how many weeks the statistics cover.
+	More  []string `toml:"more"`
+	Fewer []string `toml:"fewer"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		DatabasePath:    filepath.Join(dataHome(), "chronograph", "chronograph.db"),
+		Theme:           ThemeAuto,
+		DefaultEstimate: time.Hour,
+		BranchTemplate:  "task/{{.Name}}",
+		InferGap:        2 * time.Hour,
+		InferLeadIn:     30 * time.Minute,
+		StatsWeeks:      4,
+		IdleTimeout:     15 * time.Minute,
+		PromptFormat:    "{{.Task}} {{.Elapsed}}/{{.Estimate}}",
+		Columns:         []string{"todo", "in progress", "blocked", "in review", "done"},
+		Pomodoro: Pomodoro{
+			Work:           25 * time.Minute,
+			ShortBreak:     5 * time.Minute,
+			LongBreak:      15 * time.Minute,
+			LongBreakEvery: 4,
+		},
+		Keys: Keys{
+			Add:       []string{"a"},
+			Choose:    []string{"enter"},
+			Remove:    []string{"x", "backspace"},
+			Edit:      []string{"e"},
+			Start:     []string{"s"},
+			Pause:     []string{"p"},
+			Complete:  []string{"c"},
+			Annotate:  []string{"n"},
+			Report:    []string{"r"},
+			Archived:  []string{"A"},
+			Restore:   []string{"u"},
+			Export:    []string{"E"},
+			Infer:     []string{"I"},
+			Timesheet: []string{"T"},
+			Stats:     []string{"S"},
+			Pomodoro:  []string{"o"},
+			Board:     []string{"B"},
+			Help:      []string{"?"},
+			Up:        []string{"up", "k"},
+			Down:      []string{"down", "j"},
+			Left:      []string{"left", "h"},
+			Right:     []string{"right", "l"},
+			Back:      []string{"esc"},
+			MoveLeft:  []string{"H", "shift+left"},
+			MoveRight: []string{"L", "shift+right"},
+			Previous:  []string{"["},
+			Next:      []string{"]"},
+			Today:     []string{"t"},
+			View:      []string{"v"},
+			More:      []string{"+", "="},
+			Fewer:     []string{"-"},
+		},
+	}
+}
+
+// Path returns the location of the config file, which can be overridden with
+// the CHRONOGRAPH_CONFIG environment variable.
+func Path() string {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path
+	}
+	return filepath.Join(configHome(), "chronograph", "config.toml")
+}
+
+// Load reads the config file at path on top of the defaults and applies the
+// environment overrides. A missing config file is not an error.
+func Load(path string) (Config, error) {
+	c := Default()
+	_, err := toml.DecodeFile(path, &c)
+	c.File = path
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return c, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+	if err := c.applyEnv(); err != nil {
+		return c, err
+	}
+	c.fillKeys()
+	return c, c.Validate()
+}
+
+// applyEnv overrides settings with the environment variables that are set.
+func (c *Config) applyEnv() error {
+	if v := os.Getenv(EnvDatabase); v != "" {
+		c.DatabasePath = v
+	}
+	if v := os.Getenv(EnvTheme); v != "" {
+		c.Theme = v
+	}
+	if v := os.Getenv(EnvDefaultEstimate); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvDefaultEstimate, err)
+		}
+		c.DefaultEstimate = d
+	}
+	return nil
+}
+
+// fillKeys falls back to the default keys for actions left empty in the config file.
+func (c *Config) fillKeys() {
+	d := Default().Keys
+	for _, k := range []struct{ keys, def *[]string }{
+		{&c.Keys.Add, &d.Add},
+		{&c.Keys.Choose, &d.Choose},
+		{&c.Keys.Remove, &d.Remove},
+		{&c.Keys.Edit, &d.Edit},
+		{&c.Keys.Start, &d.Start},
+		{&c.Keys.Pause, &d.Pause},
+		{&c.Keys.Complete, &d.Complete},
+		{&c.Keys.Annotate, &d.Annotate},
+		{&c.Keys.Report, &d.Report},
+		{&c.Keys.Archived, &d.Archived},
+		{&c.Keys.Restore, &d.Restore},
+		{&c.Keys.Export, &d.Export},
+		{&c.Keys.Infer, &d.Infer},
+		{&c.Keys.Timesheet, &d.Timesheet},
+		{&c.Keys.Stats, &d.Stats},
+		{&c.Keys.Pomodoro, &d.Pomodoro},
+		{&c.Keys.Board, &d.Board},
+		{&c.Keys.Help, &d.Help},
+		{&c.Keys.Up, &d.Up},
+		{&c.Keys.Down, &d.Down},
+		{&c.Keys.Left, &d.Left},
+		{&c.Keys.Right, &d.Right},
+		{&c.Keys.Back, &d.Back},
+		{&c.Keys.MoveLeft, &d.MoveLeft},
+		{&c.Keys.MoveRight, &d.MoveRight},
+		{&c.Keys.Previous, &d.Previous},
+		{&c.Keys.Next, &d.Next},
+		{&c.Keys.Today, &d.Today},
+		{&c.Keys.View, &d.View},
+		{&c.Keys.More, &d.More},
+		{&c.Keys.Fewer, &d.Fewer},
+	} {
+		if len(*k.keys) == 0 {
+			*k.keys = *k.def
+		}
+	}
+}
+
+// Validate checks that the settings are usable.
+func (c Config) Validate() error {
+	switch c.Theme {
+	case ThemeAuto, ThemeDark, ThemeLight, ThemePlain:
+	default:
+		return fmt.Errorf("unknown theme %q, expected one of auto, dark, light or plain", c.Theme)
+	}
+	if c.DefaultEstimate < 0 {
+		return fmt.Errorf("default estimate must not be negative, got %s", c.DefaultEstimate)
+	}
+	if c.InferGap <= 0 {
+		return fmt.Errorf("infer gap must be positive, got %s", c.InferGap)
+	}
+	if c.InferLeadIn < 0 {
+		return fmt.Errorf("infer lead-in must not be negative, got %s", c.InferLeadIn)
+	}
+	if c.StatsWeeks <= 0 {
+		return fmt.Errorf("stats weeks must be positive, got %d", c.StatsWeeks)
+	}
+	if _, err := template.New("prompt").Parse(c.PromptFormat); err != nil {
+		return fmt.Errorf("parsing prompt format: %w", err)
+	}
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("idle timeout must not be negative, got %s", c.IdleTimeout)
+	}
+	if len(c.Columns) < 2 {
+		return fmt.Errorf("the board needs at least two columns, got %d", len(c.Columns))
+	}
+	seen := make(map[string]bool)
+	for _, column := range c.Columns {
+		if strings.TrimSpace(column) == "" {
+			return errors.New("board columns must not be empty")
+		}
+		if seen[column] {
+			return fmt.Errorf("board column %q is repeated", column)
+		}
+		seen[column] = true
+	}
+	if c.Pomodoro.Work <= 0 {
+		return fmt.Errorf("pomodoro work length must be positive, got %s", c.Pomodoro.Work)
+	}
+	if c.Pomodoro.ShortBreak < 0 || c.Pomodoro.LongBreak < 0 {
+		return errors.New("pomodoro breaks must not be negative")
+	}
+	if c.Pomodoro.LongBreakEvery <= 0 {
+		return fmt.Errorf("pomodoro long break cadence must be positive, got %d", c.Pomodoro.LongBreakEvery)
+	}
+	if _, err := template.New("branch").Parse(c.BranchTemplate); err != nil {
+		return fmt.Errorf("parsing branch template: %w", err)
+	}
+	return nil
+}
+
+// ExpandPath expands a leading ~ to the home directory of the user.
+func ExpandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// EnsureDatabaseDir creates the directory that holds the database, if needed.
+func (c Config) EnsureDatabaseDir() error {
+	dir := filepath.Dir(ExpandPath(c.DatabasePath))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating database directory: %w", err)
+	}
+	return nil
+}
+
+// dataHome returns $XDG_DATA_HOME, defaulting to ~/.local/share.
+func dataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(ExpandPath("~"), ".local", "share")
+}
+
+// configHome returns $XDG_CONFIG_HOME, defaulting to ~/.config.
+func configHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(ExpandPath("~"), ".config")
+}
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// writeCSV writes one row per session, which is the shape timesheets expect.
+func writeCSV(w io.Writer, workspaces []models.Workspace, now time.Time) error {
+	cw := csv.NewWriter(w)
+	header := []string{"workspace", "repo", "task", "estimate", "status", "started_at", "ended_at", "hours", "note"}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("writing csv: %w", err)
+	}
+	for _, s := range sessions(workspaces) {
+		ended := ""
+		if s.entry.EndedAt.Valid {
+			ended = s.entry.EndedAt.Time.Format(time.RFC3339)
+		}
+		err := cw.Write([]string{
+			s.workspace.Name,
+			s.repo.Name,
+			s.task.Name,
+			models.ShortDuration(s.task.ExpectedDuration),
+			s.task.Status(),
+			s.entry.StartedAt.Format(time.RFC3339),
+			ended,
+			fmt.Sprintf("%.2f", s.entry.Duration(now).Hours()),
+			s.entry.Note.String,
+		})
+		if err != nil {
+			return fmt.Errorf("writing csv: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing csv: %w", err)
+	}
+	return nil
+}
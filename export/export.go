@@ -0,0 +1,213 @@
+// Package export writes tracked time to formats that other tools understand.
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/tracker"
+)
+
+// Format is a file format that can be exported to.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+	ICS  Format = "ics"
+)
+
+// ParseFormat parses the name of a format, e.g. "csv".
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case CSV, JSON, ICS:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown export format %q, expected csv, json or ics", s)
+}
+
+// FormatFromPath returns the format matching the extension of path.
+func FormatFromPath(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return "", fmt.Errorf("cannot tell the export format of %q without a file extension", path)
+	}
+	return ParseFormat(ext)
+}
+
+// Filter selects what is exported, zero fields select everything.
+type Filter struct {
+	Workspace string
+	Repo      string
+
+	// From and To bound the sessions that are exported, sessions that
+	// overlap the range are exported in full.
+	From time.Time
+	To   time.Time
+}
+
+// dated reports whether the filter restricts the sessions by date.
+func (f Filter) dated() bool {
+	return !f.From.IsZero() || !f.To.IsZero()
+}
+
+// includes reports whether the session overlaps the date range of the filter.
+func (f Filter) includes(e models.TimeEntry, now time.Time) bool {
+	end := now
+	if e.EndedAt.Valid {
+		end = e.EndedAt.Time
+	}
+	if !f.From.IsZero() && end.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.StartedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// ParseDate parses a date as YYYY-MM-DD in local time, or as RFC 3339.
+// An empty string is the zero time, which leaves that end of a range open.
+func ParseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("parsing date %q, expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// ParseRange parses the bounds of a date range. A date without a time as
+// upper bound includes the whole day.
+func ParseRange(from, to string) (time.Time, time.Time, error) {
+	f, err := ParseDate(from)
+	if err != nil {
+		return f, time.Time{}, err
+	}
+	t, err := ParseDate(to)
+	if err != nil {
+		return f, t, err
+	}
+	if len(to) == len("2006-01-02") {
+		t = t.AddDate(0, 0, 1)
+	}
+	if !f.IsZero() && !t.IsZero() && !f.Before(t) {
+		return f, t, fmt.Errorf("the date range from %s to %s is empty", from, to)
+	}
+	return f, t, nil
+}
+
+// Collect fetches the workspaces, repos, tasks and sessions selected by the filter.
+// When the filter has a date range, tasks without sessions in it are left out.
+func Collect(s store.Store, f Filter, now time.Time) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	if f.Workspace != "" {
+		ws, err := s.WorkspaceByName(f.Workspace)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	} else {
+		var err error
+		if workspaces, err = s.Workspaces(); err != nil {
+			return nil, err
+		}
+	}
+
+	found := f.Repo == ""
+	for i := range workspaces {
+		ws, err := tracker.WorkspaceTree(s, workspaces[i], false)
+		if err != nil {
+			return nil, err
+		}
+		repos := make([]models.Repo, 0, len(ws.Repos))
+		for _, repo := range ws.Repos {
+			if f.Repo != "" && repo.Name != f.Repo {
+				continue
+			}
+			found = true
+			tasks := make([]models.Task, 0, len(repo.Tasks))
+			for _, task := range repo.Tasks {
+				entries := make([]models.TimeEntry, 0, len(task.TimeEntries))
+				for _, e := range task.TimeEntries {
+					if f.includes(e, now) {
+						entries = append(entries, e)
+					}
+				}
+				if f.dated() && len(entries) == 0 {
+					continue
+				}
+				task.TimeEntries = entries
+				tasks = append(tasks, task)
+			}
+			repo.Tasks = tasks
+			repos = append(repos, repo)
+		}
+		ws.Repos = repos
+		workspaces[i] = ws
+	}
+	if !found {
+		return nil, fmt.Errorf("fetching repo %q: %w", f.Repo, store.ErrNotFound)
+	}
+	return workspaces, nil
+}
+
+// Write writes the workspaces in the given format, open sessions are measured up to now.
+func Write(w io.Writer, format Format, workspaces []models.Workspace, now time.Time) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, workspaces, now)
+	case JSON:
+		return writeJSON(w, workspaces, now)
+	case ICS:
+		return writeICS(w, workspaces, now)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// WriteFile writes the workspaces to the file at path.
+func WriteFile(path string, format Format, workspaces []models.Workspace, now time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating export file: %w", err)
+	}
+	if err := Write(f, format, workspaces, now); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// session is a time entry together with what it was spent on.
+type session struct {
+	workspace models.Workspace
+	repo      models.Repo
+	task      models.Task
+	entry     models.TimeEntry
+}
+
+// sessions flattens the time entries of the workspaces.
+func sessions(workspaces []models.Workspace) []session {
+	var all []session
+	for _, ws := range workspaces {
+		for _, repo := range ws.Repos {
+			for _, task := range repo.Tasks {
+				for _, e := range task.TimeEntries {
+					all = append(all, session{workspace: ws, repo: repo, task: task, entry: e})
+				}
+			}
+		}
+	}
+	return all
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// icsTime is the UTC date-time format of RFC 5545.
+const icsTime = "20060102T150405Z"
+
+// writeICS writes an iCalendar with one VEVENT per session, open sessions end now.
+func writeICS(w io.Writer, workspaces []models.Workspace, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		// Lines are terminated by CRLF and folded after 75 octets, the space
+		// that continues a folded line counts towards them.
+		s := fmt.Sprintf(format, args...)
+		limit := 75
+		for len(s) > limit {
+			cut := limit
+			// do not split multi-byte characters.
+			for cut > 0 && !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+			// invalid UTF-8 has nothing to cut at but the limit.
+			if cut == 0 {
+				cut = limit
+			}
+			bw.WriteString(s[:cut] + "\r\n ")
+			s = s[cut:]
+			limit = 74
+		}
+		bw.WriteString(s + "\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//chronograph//chronograph//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, s := range sessions(workspaces) {
+		end := now
+		if s.entry.EndedAt.Valid {
+			end = s.entry.EndedAt.Time
+		}
+		description := fmt.Sprintf("%s / %s", s.workspace.Name, s.repo.Name)
+		if s.entry.Note.Valid {
+			description += "\n" + s.entry.Note.String
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:time-entry-%d@chronograph", s.entry.ID)
+		line("DTSTAMP:%s", now.UTC().Format(icsTime))
+		line("DTSTART:%s", s.entry.StartedAt.UTC().Format(icsTime))
+		line("DTEND:%s", end.UTC().Format(icsTime))
+		line("SUMMARY:%s", escapeText(s.task.Name))
+		line("DESCRIPTION:%s", escapeText(description))
+		line("CATEGORIES:%s", escapeText(s.workspace.Name))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("writing ics: %w", err)
+	}
+	return nil
+}
+
+// escapeText escapes the characters that are special in iCalendar text values.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+func TestWriteICSFolding(t *testing.T) {
+	now := time.Date(2022, 5, 2, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		task string
+	}{
+		{"short line", "tests"},
+		{"long line", strings.Repeat("fold ", 40)},
+		{"multi-byte characters", strings.Repeat("å", 30) + strings.Repeat("🕐", 30)},
+		{"invalid UTF-8", strings.Repeat("\xff", 200)},
+		{"continuation bytes without a start", strings.Repeat("\x80", 200)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaces := []models.Workspace{{Name: "work", Repos: []models.Repo{{Name: "chronograph", Tasks: []models.Task{{
+				Name:        tt.task,
+				TimeEntries: []models.TimeEntry{{StartedAt: now.Add(-time.Hour)}},
+			}}}}}}
+			var b bytes.Buffer
+			if err := Write(&b, ICS, workspaces, now); err != nil {
+				t.Fatal(err)
+			}
+
+			out := b.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("the calendar does not end with CRLF: %q", out)
+			}
+			for _, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+				if len(l) > 75 {
+					t.Errorf("line of %d octets is longer than 75: %q", len(l), l)
+				}
+				if utf8.ValidString(tt.task) && !utf8.ValidString(l) {
+					t.Errorf("line splits a character: %q", l)
+				}
+			}
+			if unfolded := strings.ReplaceAll(out, "\r\n ", ""); !strings.Contains(unfolded, "\r\nSUMMARY:"+tt.task+"\r\n") {
+				t.Errorf("the unfolded calendar does not contain the summary:\n%s", unfolded)
+			}
+		})
+	}
+}
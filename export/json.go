@@ -0,0 +1,108 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// The JSON document mirrors the hierarchy of the models, durations are in seconds.
+type (
+	workspaceJSON struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description,omitempty"`
+		Repos       []repoJSON `json:"repos"`
+	}
+
+	repoJSON struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description,omitempty"`
+		Remote      string     `json:"remote,omitempty"`
+		Path        string     `json:"path,omitempty"`
+		Tasks       []taskJSON `json:"tasks"`
+	}
+
+	taskJSON struct {
+		Name            string        `json:"name"`
+		Description     string        `json:"description,omitempty"`
+		Status          string        `json:"status"`
+		EstimateSeconds int64         `json:"estimate_seconds"`
+		TrackedSeconds  int64         `json:"tracked_seconds"`
+		StartedAt       *time.Time    `json:"started_at,omitempty"`
+		CompletedAt     *time.Time    `json:"completed_at,omitempty"`
+		Sessions        []sessionJSON `json:"sessions"`
+	}
+
+	sessionJSON struct {
+		StartedAt       time.Time  `json:"started_at"`
+		EndedAt         *time.Time `json:"ended_at,omitempty"`
+		DurationSeconds int64      `json:"duration_seconds"`
+		Note            string     `json:"note,omitempty"`
+		StartSHA        string     `json:"start_sha,omitempty"`
+		EndSHA          string     `json:"end_sha,omitempty"`
+	}
+)
+
+func writeJSON(w io.Writer, workspaces []models.Workspace, now time.Time) error {
+	doc := make([]workspaceJSON, 0, len(workspaces))
+	for _, ws := range workspaces {
+		wj := workspaceJSON{Name: ws.Name, Description: ws.Description.String, Repos: []repoJSON{}}
+		for _, repo := range ws.Repos {
+			rj := repoJSON{
+				Name:        repo.Name,
+				Description: repo.Description.String,
+				Remote:      strings.TrimSpace(repo.Remote),
+				Path:        repo.Path,
+				Tasks:       []taskJSON{},
+			}
+			for _, task := range repo.Tasks {
+				rj.Tasks = append(rj.Tasks, newTaskJSON(task, now))
+			}
+			wj.Repos = append(wj.Repos, rj)
+		}
+		doc = append(doc, wj)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("writing json: %w", err)
+	}
+	return nil
+}
+
+func newTaskJSON(task models.Task, now time.Time) taskJSON {
+	tj := taskJSON{
+		Name:            task.Name,
+		Description:     task.Description.String,
+		Status:          task.Status(),
+		EstimateSeconds: int64(task.ExpectedDuration.Seconds()),
+		TrackedSeconds:  int64(task.Tracked(now).Seconds()),
+		StartedAt:       timePtr(task.StartedAt.Time, task.StartedAt.Valid),
+		CompletedAt:     timePtr(task.CompletedAt.Time, task.CompletedAt.Valid),
+		Sessions:        []sessionJSON{},
+	}
+	for _, e := range task.TimeEntries {
+		tj.Sessions = append(tj.Sessions, sessionJSON{
+			StartedAt:       e.StartedAt,
+			EndedAt:         timePtr(e.EndedAt.Time, e.EndedAt.Valid),
+			DurationSeconds: int64(e.Duration(now).Seconds()),
+			Note:            e.Note.String,
+			StartSHA:        string(e.StartSHA),
+			EndSHA:          string(e.EndSHA),
+		})
+	}
+	return tj
+}
+
+// timePtr returns nil for times that are not set, so that they are omitted.
+func timePtr(t time.Time, valid bool) *time.Time {
+	if !valid {
+		return nil
+	}
+	return &t
+}
@@ -2,9 +2,14 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func RemoteFromPath(path string) (string, error) {
@@ -34,3 +39,446 @@ func RepoPathFromPath(path string) (string, error) {
 	}
 	return repoPath, nil
 }
+
+func HeadSHA(path string) (string, error) {
+	cmd := exec.Command("git", "-C", path, "rev-parse", "HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(`executing "git -C %s rev-parse HEAD": %v`, path, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// Commit is a single commit in the log of a repo.
+type Commit struct {
+	SHA     string
+	Author  string
+	Time    time.Time
+	Subject string
+}
+
+// ShortSHA returns the abbreviated SHA of the commit.
+func (c Commit) ShortSHA() string {
+	if len(c.SHA) < 7 {
+		return c.SHA
+	}
+	return c.SHA[:7]
+}
+
+// CommitsInRange lists the commits reachable from to but not from from, newest first.
+func CommitsInRange(path, from, to string) ([]Commit, error) {
+	rng := fmt.Sprintf("%s..%s", from, to)
+	cmd := exec.Command("git", "-C", path, "log", "--format=%H%x1f%an%x1f%at%x1f%s", rng)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf(`executing "git -C %s log %s": %v`, path, rng, err)
+	}
+	return parseLog(out.String())
+}
+
+// CommitAt returns the commit that rev points to.
+func CommitAt(path, rev string) (Commit, error) {
+	cmd := exec.Command("git", "-C", path, "log", "-1", "--format=%H%x1f%an%x1f%at%x1f%s", rev)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return Commit{}, fmt.Errorf(`executing "git -C %s log -1 %s": %v`, path, rev, err)
+	}
+	commits, err := parseLog(out.String())
+	if err != nil {
+		return Commit{}, err
+	}
+	if len(commits) != 1 {
+		return Commit{}, fmt.Errorf("no commit at %s", rev)
+	}
+	return commits[0], nil
+}
+
+// Trailers returns the values of the trailers with key in the message of the commit at rev.
+func Trailers(path, rev, key string) ([]string, error) {
+	format := fmt.Sprintf("--format=%%(trailers:key=%s,valueonly)", key)
+	cmd := exec.Command("git", "-C", path, "log", "-1", format, rev)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf(`executing "git -C %s log -1 %s %s": %v`, path, format, rev, err)
+	}
+	var values []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return values, nil
+}
+
+// AddTrailer adds a trailer like "Task: name" to the commit message in file,
+// unless the message already has it.
+func AddTrailer(path, file, trailer string) error {
+	cmd := exec.Command("git", "-C", path, "interpret-trailers", "--in-place", "--if-exists", "addIfDifferent", "--trailer", trailer, file)
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(`executing "git -C %s interpret-trailers --trailer %s %s": %v`, path, trailer, file, err)
+	}
+	return nil
+}
+
+// TopLevel returns the root of the working tree that path is in.
+func TopLevel(path string) (string, error) {
+	cmd := exec.Command("git", "-C", path, "rev-parse", "--show-toplevel")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(`executing "git -C %s rev-parse --show-toplevel": %v`, path, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// HooksDir returns the directory that git runs the hooks of the repo at path from.
+func HooksDir(path string) (string, error) {
+	return gitPath(path, "hooks")
+}
+
+// gitPath resolves name inside the git directory of the repo at path.
+func gitPath(path, name string) (string, error) {
+	cmd := exec.Command("git", "-C", path, "rev-parse", "--git-path", name)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(`executing "git -C %s rev-parse --git-path %s": %v`, path, name, err)
+	}
+	dir := strings.TrimSpace(out.String())
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(path, dir)
+	}
+	return dir, nil
+}
+
+// parseLog parses the output of git log with fields separated by the unit separator.
+func parseLog(out string) ([]Commit, error) {
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected git log line %q", line)
+		}
+		unix, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing commit time %q: %v", fields[2], err)
+		}
+		commits = append(commits, Commit{
+			SHA:     fields[0],
+			Author:  fields[1],
+			Time:    time.Unix(unix, 0),
+			Subject: fields[3],
+		})
+	}
+	return commits, nil
+}
+
+// ShortStat summarizes the changes between two revisions.
+type ShortStat struct {
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+}
+
+// ShortStatInRange computes the number of changed files, insertions and deletions between from and to.
+func ShortStatInRange(path, from, to string) (ShortStat, error) {
+	cmd := exec.Command("git", "-C", path, "diff", "--shortstat", from, to)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return ShortStat{}, fmt.Errorf(`executing "git -C %s diff --shortstat %s %s": %v`, path, from, to, err)
+	}
+	return parseShortStat(out.String())
+}
+
+// ShortStatOf sums the changed files, insertions and deletions of the given commits.
+func ShortStatOf(path string, shas []string) (ShortStat, error) {
+	var stat ShortStat
+	if len(shas) == 0 {
+		return stat, nil
+	}
+	args := append([]string{"-C", path, "show", "--shortstat", "--format="}, shas...)
+	cmd := exec.Command("git", args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return stat, fmt.Errorf(`executing "git -C %s show --shortstat": %v`, path, err)
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		s, err := parseShortStat(line)
+		if err != nil {
+			return stat, err
+		}
+		stat.FilesChanged += s.FilesChanged
+		stat.Insertions += s.Insertions
+		stat.Deletions += s.Deletions
+	}
+	return stat, nil
+}
+
+// parseShortStat parses lines like " 3 files changed, 10 insertions(+), 2 deletions(-)".
+func parseShortStat(out string) (ShortStat, error) {
+	var stat ShortStat
+	for _, part := range strings.Split(strings.TrimSpace(out), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var n int
+		var what string
+		if _, err := fmt.Sscanf(part, "%d %s", &n, &what); err != nil {
+			return stat, fmt.Errorf("parsing shortstat %q: %v", part, err)
+		}
+		switch {
+		case strings.HasPrefix(what, "file"):
+			stat.FilesChanged = n
+		case strings.HasPrefix(what, "insertion"):
+			stat.Insertions = n
+		case strings.HasPrefix(what, "deletion"):
+			stat.Deletions = n
+		}
+	}
+	return stat, nil
+}
+
+// CurrentBranch returns the branch checked out at path, empty if HEAD is detached.
+func CurrentBranch(path string) (string, error) {
+	cmd := exec.Command("git", "-C", path, "symbolic-ref", "--short", "-q", "HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf(`executing "git -C %s symbolic-ref --short HEAD": %v`, path, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// BranchExists reports whether the repo at path has a local branch with name.
+func BranchExists(path, name string) (bool, error) {
+	ref := "refs/heads/" + name
+	cmd := exec.Command("git", "-C", path, "show-ref", "--verify", "--quiet", ref)
+
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf(`executing "git -C %s show-ref --verify %s": %v`, path, ref, err)
+	}
+	return true, nil
+}
+
+// CheckBranchName reports an error if name is not a valid branch name.
+func CheckBranchName(name string) error {
+	cmd := exec.Command("git", "check-ref-format", "--branch", name)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", name)
+	}
+	return nil
+}
+
+// Checkout checks out the branch name, creating it from HEAD if create is set.
+func Checkout(path, name string, create bool) error {
+	args := []string{"-C", path, "checkout", "-q"}
+	if create {
+		args = append(args, "-b")
+	}
+	args = append(args, name)
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(`executing "git %s": %v: %s`, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// DefaultBranch returns the branch that work is merged into, which is the
+// HEAD of origin if it is known, otherwise main or master.
+func DefaultBranch(path string) (string, error) {
+	cmd := exec.Command("git", "-C", path, "symbolic-ref", "--short", "-q", "refs/remotes/origin/HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil {
+		return strings.TrimSpace(out.String()), nil
+	}
+	for _, name := range []string{"main", "master"} {
+		exists, err := BranchExists(path, name)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("finding the default branch of %s: neither origin/HEAD, main nor master exist", path)
+}
+
+// RevParse returns the SHA that rev points to.
+func RevParse(path, rev string) (string, error) {
+	cmd := exec.Command("git", "-C", path, "rev-parse", "--verify", "-q", rev+"^{commit}")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(`executing "git -C %s rev-parse %s": %v`, path, rev, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// IsAncestor reports whether the commit at ancestor is reachable from rev.
+func IsAncestor(path, ancestor, rev string) (bool, error) {
+	cmd := exec.Command("git", "-C", path, "merge-base", "--is-ancestor", ancestor, rev)
+
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf(`executing "git -C %s merge-base --is-ancestor %s %s": %v`, path, ancestor, rev, err)
+	}
+	return true, nil
+}
+
+// UserEmail returns the email that commits in the repo at path are authored with.
+func UserEmail(path string) (string, error) {
+	cmd := exec.Command("git", "-C", path, "config", "--get", "user.email")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(`executing "git -C %s config --get user.email": %v`, path, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// CommitsBetween lists the commits on any branch that author made between
+// since and until, in the order of git log, which need not be the order of
+// their author dates.
+func CommitsBetween(path, author string, since, until time.Time) ([]Commit, error) {
+	// git filters on the commit date, which rebasing and cherry-picking move
+	// past the author date. Commits are committed after they are authored, so
+	// only the ones from before since are left out by git.
+	args := []string{
+		"-C", path, "log", "--all", "--reverse",
+		"--format=%H%x1f%an%x1f%at%x1f%s",
+		"--author=" + author,
+		"--since=" + since.Format(time.RFC3339),
+	}
+	cmd := exec.Command("git", args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf(`executing "git -C %s log --all --author=%s": %v`, path, author, err)
+	}
+	commits, err := parseLog(out.String())
+	if err != nil {
+		return nil, err
+	}
+	// the author date is when the work was done.
+	in := commits[:0]
+	for _, c := range commits {
+		if !c.Time.Before(since) && c.Time.Before(until) {
+			in = append(in, c)
+		}
+	}
+	return in, nil
+}
+
+// BranchesContaining lists the local branches that rev is reachable from.
+func BranchesContaining(path, rev string) ([]string, error) {
+	cmd := exec.Command("git", "-C", path, "branch", "--format=%(refname:short)", "--contains", rev)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf(`executing "git -C %s branch --contains %s": %v`, path, rev, err)
+	}
+	return strings.Fields(out.String()), nil
+}
+
+// LastChange returns when the work in the repo at path last changed, which is
+// the latest of the HEAD commit, the index and the modified or untracked files.
+func LastChange(path string) (time.Time, error) {
+	var last time.Time
+	if head, err := CommitAt(path, "HEAD"); err == nil {
+		last = head.Time
+	}
+	if index, err := gitPath(path, "index"); err == nil {
+		if info, err := os.Stat(index); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+
+	cmd := exec.Command("git", "-C", path, "ls-files", "-z", "--modified", "--others", "--exclude-standard")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return last, fmt.Errorf(`executing "git -C %s ls-files --modified --others": %v`, path, err)
+	}
+	for _, name := range strings.Split(out.String(), "\x00") {
+		if name == "" {
+			continue
+		}
+		// deleted files are listed as modified, but have nothing to stat.
+		if info, err := os.Stat(filepath.Join(path, name)); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// SHABefore returns the SHA of the latest commit on HEAD that was made before t,
+// or HEAD itself if there is none.
+func SHABefore(path string, t time.Time) (string, error) {
+	cmd := exec.Command("git", "-C", path, "rev-list", "-1", "--before="+t.Format(time.RFC3339), "HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(`executing "git -C %s rev-list --before": %v`, path, err)
+	}
+	if sha := strings.TrimSpace(out.String()); sha != "" {
+		return sha, nil
+	}
+	return HeadSHA(path)
+}
@@ -2,16 +2,20 @@ module github.com/mellonnen/chronograph
 
 go 1.18
 
-require github.com/charmbracelet/bubbles v0.10.3
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/charmbracelet/bubbles v0.11.0
+)
 
 require (
 	github.com/atotto/clipboard v0.1.4 // indirect
+	github.com/muesli/cancelreader v0.2.0 // indirect
 	github.com/sahilm/fuzzy v0.1.0 // indirect
 )
 
 require (
-	github.com/charmbracelet/bubbletea v0.20.0
-	github.com/charmbracelet/lipgloss v0.4.0
+	github.com/charmbracelet/bubbletea v0.21.0
+	github.com/charmbracelet/lipgloss v0.5.0
 	github.com/containerd/console v1.0.3 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
@@ -23,8 +27,8 @@ require (
 	github.com/muesli/reflow v0.3.0 // indirect
 	github.com/muesli/termenv v0.11.1-0.20220212125758-44cd13922739 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
-	golang.org/x/term v0.0.0-20210422114643-f5beecf764ed // indirect
+	golang.org/x/sys v0.0.0-20220209214540-3681064d5158 // indirect
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
 	gorm.io/driver/sqlite v1.3.2
 	gorm.io/gorm v1.23.4
 )
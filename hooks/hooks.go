@@ -0,0 +1,233 @@
+// Package hooks installs git hooks that link commits to the running task, and
+// implements what the hooks do when git runs them.
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mellonnen/chronograph/git"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+// TrailerKey is the key of the commit message trailer that names the task.
+const TrailerKey = "Task"
+
+// marker identifies the hooks written by chronograph.
+const marker = "# Installed by chronograph"
+
+// Names are the hooks that chronograph installs.
+var Names = []string{"prepare-commit-msg", "post-commit"}
+
+// ErrForeignHook is returned when a hook that chronograph did not write is in the way.
+var ErrForeignHook = errors.New("hook was not installed by chronograph")
+
+// script returns the hook that runs `chrono hooks <name>` with its arguments,
+// where command is the chrono binary followed by its global flags. Failures are
+// ignored, so that chronograph never stands in the way of a commit.
+func script(name string, command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return fmt.Sprintf("#!/bin/sh\n%s, remove with `chrono hooks uninstall`.\n%s hooks %s \"$@\" || true\n", marker, strings.Join(quoted, " "), name)
+}
+
+// Install writes the hooks into the repo at repoPath, which run command: the
+// chrono binary followed by the flags that select its config and database, so
+// that the hooks use the same database however git is run, e.g. by an editor
+// without the environment of the shell. Existing hooks that were not installed
+// by chronograph are only replaced when force is set, after being backed up
+// with a .bak suffix. It returns the paths of the installed hooks.
+func Install(repoPath string, command []string, force bool) ([]string, error) {
+	dir, err := git.HooksDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating hooks directory: %w", err)
+	}
+
+	paths := make([]string, 0, len(Names))
+	for _, name := range Names {
+		path := filepath.Join(dir, name)
+		ours, err := installed(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return paths, err
+		case !ours && !force:
+			return paths, fmt.Errorf("installing %s: %w, use -force to back it up and replace it", path, ErrForeignHook)
+		case !ours:
+			if err := os.Rename(path, path+".bak"); err != nil {
+				return paths, fmt.Errorf("backing up %s: %w", path, err)
+			}
+		}
+		if err := os.WriteFile(path, []byte(script(name, command)), 0o755); err != nil {
+			return paths, fmt.Errorf("installing %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// Uninstall removes the hooks installed by chronograph from the repo at repoPath,
+// and restores the hooks they replaced. It returns the paths of the removed hooks.
+func Uninstall(repoPath string) ([]string, error) {
+	dir, err := git.HooksDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, name := range Names {
+		path := filepath.Join(dir, name)
+		ours, err := installed(path)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && !ours) {
+			continue
+		}
+		if err != nil {
+			return paths, err
+		}
+		if err := os.Remove(path); err != nil {
+			return paths, fmt.Errorf("removing %s: %w", path, err)
+		}
+		if _, err := os.Stat(path + ".bak"); err == nil {
+			if err := os.Rename(path+".bak", path); err != nil {
+				return paths, fmt.Errorf("restoring %s: %w", path, err)
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// installed reports whether the hook at path was written by chronograph.
+func installed(path string) (bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(b), marker), nil
+}
+
+// RepoAt returns the tracked repo whose working tree contains dir.
+func RepoAt(s store.Store, dir string) (models.Repo, error) {
+	top, err := git.TopLevel(dir)
+	if err != nil {
+		return models.Repo{}, err
+	}
+	repos, err := s.Repos(0)
+	if err != nil {
+		return models.Repo{}, err
+	}
+	for _, r := range repos {
+		if r.Path != "" && filepath.Clean(r.Path) == filepath.Clean(top) {
+			return r, nil
+		}
+	}
+	return models.Repo{}, fmt.Errorf("fetching repo at %s: %w", top, store.ErrNotFound)
+}
+
+// runningTasks returns the tasks of the repo that are running.
+func runningTasks(s store.Store, repo models.Repo) ([]models.Task, error) {
+	running, err := s.RunningTasks()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	for _, t := range running {
+		if t.RepoID == repo.ID {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+// tasksNamed returns the tasks of the repo with the names, as tasks of other
+// repos may share them.
+func tasksNamed(s store.Store, repo models.Repo, names []string) ([]models.Task, error) {
+	all, err := s.Tasks(repo.ID)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	for _, name := range names {
+		found := false
+		for _, t := range all {
+			if t.Name == name {
+				tasks = append(tasks, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("fetching task %q of %s: %w", name, repo.Name, store.ErrNotFound)
+		}
+	}
+	return tasks, nil
+}
+
+// PrepareCommitMsg adds a trailer naming each running task of the repo to the
+// commit message in file. Editing the trailers before committing decides which
+// tasks the commit is linked to.
+func PrepareCommitMsg(s store.Store, dir, file string) error {
+	repo, err := RepoAt(s, dir)
+	if err != nil {
+		return err
+	}
+	tasks, err := runningTasks(s, repo)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		if err := git.AddTrailer(dir, file, fmt.Sprintf("%s: %s", TrailerKey, t.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostCommit links the commit at HEAD to the tasks named by its trailers, or
+// to the running tasks of the repo if it has none. It returns the linked tasks.
+func PostCommit(s store.Store, dir string) ([]models.Task, error) {
+	repo, err := RepoAt(s, dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := git.Trailers(dir, "HEAD", TrailerKey)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	if len(names) == 0 {
+		if tasks, err = runningTasks(s, repo); err != nil {
+			return nil, err
+		}
+	} else if tasks, err = tasksNamed(s, repo, names); err != nil {
+		return nil, err
+	}
+
+	commit, err := git.CommitAt(dir, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		err := s.CreateTaskCommit(&models.TaskCommit{
+			TaskID:      t.ID,
+			SHA:         commit.SHA,
+			Author:      commit.Author,
+			Subject:     commit.Subject,
+			CommittedAt: commit.Time,
+		})
+		// Amending or rebasing can run the hook for the same commit again.
+		if err != nil && !errors.Is(err, store.ErrDuplicate) {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
@@ -0,0 +1,222 @@
+// Package importer brings the history of other time trackers into chronograph.
+package importer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+// Record is a single session read from another time tracker, along with the
+// names of the workspace, repo and task it belongs to.
+type Record struct {
+	Workspace string
+	Repo      string
+	Task      string
+	Note      string
+
+	Start time.Time
+	End   time.Time
+}
+
+// Parser reads the records of an export file.
+type Parser func(r io.Reader) ([]Record, error)
+
+// Parsers maps the supported sources to their parsers.
+var Parsers = map[string]Parser{
+	"timewarrior": ParseTimewarrior,
+	"toggl":       ParseToggl,
+}
+
+// Result summarizes an import.
+type Result struct {
+	Workspaces int
+	Repos      int
+	Tasks      int
+	Sessions   int
+	// Duplicates counts the sessions that had been imported before.
+	Duplicates int
+
+	// Created describes everything that was created, in order.
+	Created []string
+}
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// Import creates the workspaces, repos, tasks and sessions of the records that
+// do not exist yet. Sessions are identified by their task and start time, so
+// importing the same file twice does not duplicate them. A dry run reports what
+// would be created without persisting anything.
+func Import(s store.Store, source string, records []Record, dryRun bool) (Result, error) {
+	var res Result
+	err := s.Transaction(func(tx store.Store) error {
+		im := importer{
+			s:          tx,
+			source:     source,
+			res:        &res,
+			workspaces: make(map[string]models.Workspace),
+			repos:      make(map[string]models.Repo),
+			tasks:      make(map[string]models.Task),
+		}
+		for _, r := range records {
+			if err := im.add(r); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return res, fmt.Errorf("importing from %s: %w", source, err)
+	}
+	return res, nil
+}
+
+// importer finds or creates the records of an import, caching them by name.
+type importer struct {
+	s      store.Store
+	source string
+	res    *Result
+
+	workspaces map[string]models.Workspace
+	repos      map[string]models.Repo
+	tasks      map[string]models.Task
+}
+
+func (im *importer) add(r Record) error {
+	if !r.End.After(r.Start) {
+		return fmt.Errorf("session of %q ends before it starts", r.Task)
+	}
+	ws, err := im.workspace(r.Workspace)
+	if err != nil {
+		return err
+	}
+	repo, err := im.repo(ws, r.Repo)
+	if err != nil {
+		return err
+	}
+	task, err := im.task(repo, r.Task)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range task.TimeEntries {
+		if e.StartedAt.Equal(r.Start) {
+			im.res.Duplicates++
+			return nil
+		}
+	}
+	entry := models.TimeEntry{
+		TaskID:    task.ID,
+		StartedAt: r.Start,
+		EndedAt:   sql.NullTime{Time: r.End, Valid: true},
+		Note:      sql.NullString{String: r.Note, Valid: r.Note != ""},
+	}
+	if err := im.s.CreateTimeEntry(&entry); err != nil {
+		return err
+	}
+	task.TimeEntries = append(task.TimeEntries, entry)
+	im.res.Sessions++
+	im.res.Created = append(im.res.Created, fmt.Sprintf("session %s %s - %s",
+		task.Name, r.Start.Local().Format("2006-01-02 15:04"), r.End.Local().Format("15:04")))
+
+	// The task counts as started from its earliest session.
+	if !task.StartedAt.Valid || r.Start.Before(task.StartedAt.Time) {
+		task.StartedAt = sql.NullTime{Time: r.Start, Valid: true}
+		if err := im.s.UpdateTask(&task); err != nil {
+			return err
+		}
+	}
+	im.tasks[task.Name] = task
+	return nil
+}
+
+func (im *importer) workspace(name string) (models.Workspace, error) {
+	if ws, ok := im.workspaces[name]; ok {
+		return ws, nil
+	}
+	ws, err := im.s.WorkspaceByName(name)
+	if errors.Is(err, store.ErrNotFound) {
+		ws = models.Workspace{Name: name, Description: im.description()}
+		if err = im.s.CreateWorkspace(&ws); err == nil {
+			im.res.Workspaces++
+			im.res.Created = append(im.res.Created, fmt.Sprintf("workspace %s", name))
+		}
+	}
+	if err != nil {
+		return ws, err
+	}
+	im.workspaces[name] = ws
+	return ws, nil
+}
+
+// repo finds or creates a repo in the workspace. Repo names are unique across
+// workspaces, so a name used by another workspace is qualified with the
+// name of this one.
+func (im *importer) repo(ws models.Workspace, name string) (models.Repo, error) {
+	for _, n := range []string{name, fmt.Sprintf("%s/%s", ws.Name, name)} {
+		if repo, ok := im.repos[n]; ok && repo.WorkspaceID == ws.ID {
+			return repo, nil
+		}
+		repo, err := im.s.RepoByName(n)
+		switch {
+		case err == nil && repo.WorkspaceID == ws.ID:
+			im.repos[n] = repo
+			return repo, nil
+		case err == nil:
+			continue
+		case !errors.Is(err, store.ErrNotFound):
+			return repo, err
+		}
+		repo = models.Repo{WorkspaceID: ws.ID, Name: n, Description: im.description()}
+		if err := im.s.CreateRepo(&repo); err != nil {
+			return repo, err
+		}
+		im.res.Repos++
+		im.res.Created = append(im.res.Created, fmt.Sprintf("repo %s in %s", n, ws.Name))
+		im.repos[n] = repo
+		return repo, nil
+	}
+	return models.Repo{}, fmt.Errorf("creating repo %q: %w", name, store.ErrDuplicate)
+}
+
+// task finds or creates a task in the repo, qualifying names used by other
+// repos like repo does.
+func (im *importer) task(repo models.Repo, name string) (models.Task, error) {
+	for _, n := range []string{name, fmt.Sprintf("%s/%s", repo.Name, name)} {
+		if task, ok := im.tasks[n]; ok && task.RepoID == repo.ID {
+			return task, nil
+		}
+		task, err := im.s.TaskByName(n)
+		switch {
+		case err == nil && task.RepoID == repo.ID:
+			im.tasks[n] = task
+			return task, nil
+		case err == nil:
+			continue
+		case !errors.Is(err, store.ErrNotFound):
+			return task, err
+		}
+		task = models.Task{RepoID: repo.ID, Name: n, Description: im.description()}
+		if err := im.s.CreateTask(&task); err != nil {
+			return task, err
+		}
+		im.res.Tasks++
+		im.res.Created = append(im.res.Created, fmt.Sprintf("task %s in %s", n, repo.Name))
+		im.tasks[n] = task
+		return task, nil
+	}
+	return models.Task{}, fmt.Errorf("creating task %q: %w", name, store.ErrDuplicate)
+}
+
+func (im *importer) description() sql.NullString {
+	return sql.NullString{String: fmt.Sprintf("Imported from %s", im.source), Valid: true}
+}
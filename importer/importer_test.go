@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/tracker"
+)
+
+func TestImport(t *testing.T) {
+	day := time.Date(2022, 5, 2, 9, 0, 0, 0, time.UTC)
+	record := func(workspace, repo, task string, hour int) Record {
+		start := day.Add(time.Duration(hour) * time.Hour)
+		return Record{Workspace: workspace, Repo: repo, Task: task, Start: start, End: start.Add(30 * time.Minute)}
+	}
+	tests := []struct {
+		name string
+		// existing are imported before the records, in a separate import.
+		existing []Record
+		records  []Record
+		dryRun   bool
+		want     Result
+		// repos and tasks are the names the records end up under.
+		repos, tasks []string
+	}{
+		{
+			name:    "new records",
+			records: []Record{record("work", "api", "auth", 0), record("work", "api", "auth", 1), record("work", "api", "docs", 2)},
+			want:    Result{Workspaces: 1, Repos: 1, Tasks: 2, Sessions: 3},
+			repos:   []string{"api"},
+			tasks:   []string{"auth", "docs"},
+		},
+		{
+			name:     "sessions imported before",
+			existing: []Record{record("work", "api", "auth", 0)},
+			records:  []Record{record("work", "api", "auth", 0), record("work", "api", "auth", 1)},
+			want:     Result{Sessions: 1, Duplicates: 1},
+			repos:    []string{"api"},
+			tasks:    []string{"auth"},
+		},
+		{
+			name:    "sessions repeated in the file",
+			records: []Record{record("work", "api", "auth", 0), record("work", "api", "auth", 0)},
+			want:    Result{Workspaces: 1, Repos: 1, Tasks: 1, Sessions: 1, Duplicates: 1},
+			repos:   []string{"api"},
+			tasks:   []string{"auth"},
+		},
+		{
+			name:     "names used in another workspace",
+			existing: []Record{record("work", "api", "auth", 0)},
+			records:  []Record{record("home", "api", "auth", 0)},
+			want:     Result{Workspaces: 1, Repos: 1, Tasks: 1, Sessions: 1},
+			repos:    []string{"api", "home/api"},
+			tasks:    []string{"auth", "home/api/auth"},
+		},
+		{
+			name:    "dry run",
+			records: []Record{record("work", "api", "auth", 0)},
+			dryRun:  true,
+			want:    Result{Workspaces: 1, Repos: 1, Tasks: 1, Sessions: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store.NewMemory()
+			if _, err := Import(s, "test", tt.existing, false); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Import(s, "test", tt.records, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Workspaces != tt.want.Workspaces || got.Repos != tt.want.Repos || got.Tasks != tt.want.Tasks ||
+				got.Sessions != tt.want.Sessions || got.Duplicates != tt.want.Duplicates {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if n := tt.want.Workspaces + tt.want.Repos + tt.want.Tasks + tt.want.Sessions; len(got.Created) != n {
+				t.Errorf("described %d created records, want %d", len(got.Created), n)
+			}
+
+			repos, err := s.Repos(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := names(repos); !equal(names, tt.repos) {
+				t.Errorf("got repos %v, want %v", names, tt.repos)
+			}
+			tasks, err := s.Tasks(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := names(tasks); !equal(names, tt.tasks) {
+				t.Errorf("got tasks %v, want %v", names, tt.tasks)
+			}
+			var sessions int
+			for _, task := range tasks {
+				sessions += len(task.TimeEntries)
+			}
+			if want := len(tt.existing) + tt.want.Sessions; !tt.dryRun && sessions != want {
+				t.Errorf("stored %d sessions, want %d", sessions, want)
+			}
+		})
+	}
+}
+
+func TestImportRejectsBackwardsSessions(t *testing.T) {
+	now := time.Now()
+	records := []Record{{Workspace: "work", Repo: "api", Task: "auth", Start: now, End: now.Add(-time.Hour)}}
+	s := store.NewMemory()
+	if _, err := Import(s, "test", records, false); err == nil {
+		t.Fatal("importing a session that ends before it starts succeeded")
+	}
+	if workspaces, _ := s.Workspaces(); len(workspaces) != 0 {
+		t.Errorf("a failed import left %d workspaces behind", len(workspaces))
+	}
+}
+
+func TestImportedTasksCanBeTracked(t *testing.T) {
+	start := time.Now().Add(-2 * time.Hour)
+	records := []Record{{Workspace: "work", Repo: "api", Task: "auth", Start: start, End: start.Add(time.Hour)}}
+	s := store.NewMemory()
+	if _, err := Import(s, "test", records, false); err != nil {
+		t.Fatal(err)
+	}
+	task, err := s.TaskByName("auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := s.Repo(task.RepoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the repo has no path, so none of the git features may run in the
+	// working directory instead.
+	if task, _, err = tracker.Switch(s, task, repo.Path, ""); err != nil {
+		t.Fatalf("starting an imported task: %v", err)
+	}
+	if _, err := tracker.CheckoutBranch(s, task, repo.Path, "task/auth"); !errors.Is(err, tracker.ErrNoPath) {
+		t.Errorf("checking out a branch returned %v, want %v", err, tracker.ErrNoPath)
+	}
+	if _, err := tracker.Infer(s, repo, start, time.Now(), time.Hour, time.Hour); !errors.Is(err, tracker.ErrNoPath) {
+		t.Errorf("inferring sessions returned %v, want %v", err, tracker.ErrNoPath)
+	}
+	if task, err = tracker.Complete(s, task, repo.Path); err != nil {
+		t.Fatalf("completing an imported task: %v", err)
+	}
+	if len(task.TimeEntries) != 2 || len(task.StartSHA) != 0 || len(task.EndSHA) != 0 {
+		t.Errorf("got %d sessions and SHAs %q..%q, want 2 sessions and no SHAs", len(task.TimeEntries), task.StartSHA, task.EndSHA)
+	}
+}
+
+func names[L models.Listable](resources []L) []string {
+	var names []string
+	for _, r := range resources {
+		names = append(names, r.GetName())
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// timewarriorTime is the date format of `timew export`.
+const timewarriorTime = "20060102T150405Z"
+
+// timewarriorInterval is an interval as written by `timew export`.
+type timewarriorInterval struct {
+	ID         int      `json:"id"`
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Tags       []string `json:"tags"`
+	Annotation string   `json:"annotation"`
+}
+
+// ParseTimewarrior reads the JSON written by `timew export`. The first tag of
+// an interval names its workspace, which holds a single repo of the same name,
+// and the remaining tags name the task. Intervals that are still open are skipped.
+func ParseTimewarrior(r io.Reader) ([]Record, error) {
+	var intervals []timewarriorInterval
+	if err := json.NewDecoder(r).Decode(&intervals); err != nil {
+		return nil, fmt.Errorf("reading timewarrior export: %w", err)
+	}
+	records := make([]Record, 0, len(intervals))
+	for _, in := range intervals {
+		if in.End == "" {
+			continue
+		}
+		start, err := time.Parse(timewarriorTime, in.Start)
+		if err != nil {
+			return nil, fmt.Errorf("reading start of interval %d: %w", in.ID, err)
+		}
+		end, err := time.Parse(timewarriorTime, in.End)
+		if err != nil {
+			return nil, fmt.Errorf("reading end of interval %d: %w", in.ID, err)
+		}
+
+		workspace, task := "timewarrior", "untagged"
+		switch len(in.Tags) {
+		case 0:
+		case 1:
+			workspace = in.Tags[0]
+			task = in.Tags[0]
+		default:
+			workspace = in.Tags[0]
+			task = strings.Join(in.Tags[1:], " ")
+		}
+		records = append(records, Record{
+			Workspace: workspace,
+			Repo:      workspace,
+			Task:      task,
+			Note:      in.Annotation,
+			Start:     start,
+			End:       end,
+		})
+	}
+	return records, nil
+}
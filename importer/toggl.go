@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseToggl reads a detailed report exported from Toggl as CSV. The project of
+// an entry names its workspace, which holds a single repo of the same name, and
+// the description names the task. The tags are kept as the note of the session.
+// Times are read in the local time zone, which is how Toggl writes them.
+func ParseToggl(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading toggl export: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		// Toggl starts the file with a byte order mark.
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, c := range []string{"project", "description", "start date", "start time", "end date", "end time"} {
+		if _, ok := columns[c]; !ok {
+			return nil, fmt.Errorf("reading toggl export: missing column %q, is it a detailed report?", c)
+		}
+	}
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	records := make([]Record, 0, len(rows)-1)
+	for n, row := range rows[1:] {
+		start, err := time.ParseInLocation("2006-01-02 15:04:05", field(row, "start date")+" "+field(row, "start time"), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("reading start of row %d: %w", n+2, err)
+		}
+		end, err := time.ParseInLocation("2006-01-02 15:04:05", field(row, "end date")+" "+field(row, "end time"), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("reading end of row %d: %w", n+2, err)
+		}
+
+		workspace := field(row, "project")
+		if workspace == "" {
+			workspace = "toggl"
+		}
+		task := field(row, "description")
+		if task == "" {
+			task = field(row, "task")
+		}
+		if task == "" {
+			task = "untitled"
+		}
+		records = append(records, Record{
+			Workspace: workspace,
+			Repo:      workspace,
+			Task:      task,
+			Note:      field(row, "tags"),
+			Start:     start,
+			End:       end,
+		})
+	}
+	return records, nil
+}
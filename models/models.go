@@ -2,12 +2,14 @@ package models
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Listable interface {
+	GetID() uint
 	GetName() string
 	GetDescription() string
 	GetCreatedAt() string
@@ -15,12 +17,13 @@ type Listable interface {
 
 type Workspace struct {
 	gorm.Model
-	Name        string `gorm:"unique"`
+	Name        string `gorm:"uniqueIndex:idx_workspaces_name,where:deleted_at IS NULL"`
 	Description sql.NullString
 
-	Repos []Repo
+	Repos []Repo `gorm:"constraint:OnDelete:CASCADE"`
 }
 
+func (w Workspace) GetID() uint     { return w.ID }
 func (w Workspace) GetName() string { return w.Name }
 func (w Workspace) GetDescription() string {
 	if w.Description.Valid {
@@ -34,14 +37,15 @@ type Repo struct {
 	gorm.Model
 	WorkspaceID uint
 
-	Name        string `gorm:"unique"`
+	Name        string `gorm:"uniqueIndex:idx_repos_name,where:deleted_at IS NULL"`
 	Description sql.NullString
 	Remote      string
 	Path        string
 
-	Tasks []Task
+	Tasks []Task `gorm:"constraint:OnDelete:CASCADE"`
 }
 
+func (r Repo) GetID() uint     { return r.ID }
 func (r Repo) GetName() string { return r.Name }
 func (r Repo) GetDescription() string {
 	if r.Description.Valid {
@@ -54,7 +58,7 @@ func (r Repo) GetCreatedAt() string { return r.CreatedAt.Format("January 2, 2006
 type Task struct {
 	gorm.Model
 	RepoID      uint
-	Name        string `gorm:"unique"`
+	Name        string `gorm:"uniqueIndex:idx_tasks_name,where:deleted_at IS NULL"`
 	Description sql.NullString
 
 	StartedAt        sql.NullTime
@@ -63,8 +67,19 @@ type Task struct {
 
 	StartSHA []byte
 	EndSHA   []byte
+	// Branch is the git branch the task is worked on, if any.
+	Branch string
+	// State is the column of the board the task was last moved to, empty if
+	// it has never been moved. See StateIn.
+	State string
+
+	TimeEntries  []TimeEntry   `gorm:"constraint:OnDelete:CASCADE"`
+	Commits      []TaskCommit  `gorm:"constraint:OnDelete:CASCADE"`
+	Tags         []Tag         `gorm:"many2many:task_tags"`
+	StateChanges []StateChange `gorm:"constraint:OnDelete:CASCADE"`
 }
 
+func (t Task) GetID() uint     { return t.ID }
 func (t Task) GetName() string { return t.Name }
 func (t Task) GetDescription() string {
 	if t.Description.Valid {
@@ -73,3 +88,204 @@ func (t Task) GetDescription() string {
 	return "No description available"
 }
 func (t Task) GetCreatedAt() string { return t.CreatedAt.Format("January 2, 2006, 15:30") }
+
+// ActiveEntry returns the currently open time entry of the task, or nil if the
+// task is not being worked on.
+func (t Task) ActiveEntry() *TimeEntry {
+	for i := range t.TimeEntries {
+		if !t.TimeEntries[i].EndedAt.Valid {
+			return &t.TimeEntries[i]
+		}
+	}
+	return nil
+}
+
+// Running reports whether the task currently has an open time entry.
+func (t Task) Running() bool { return t.ActiveEntry() != nil }
+
+// Tracked returns the total time tracked across all entries of the task,
+// open entries are measured up to now.
+func (t Task) Tracked(now time.Time) time.Duration {
+	var d time.Duration
+	for _, e := range t.TimeEntries {
+		d += e.Duration(now)
+	}
+	return d
+}
+
+// Pomodoros returns the number of pomodoros that were finished on the task.
+func (t Task) Pomodoros() int {
+	n := 0
+	for _, e := range t.TimeEntries {
+		if e.Pomodoro && e.EndedAt.Valid {
+			n++
+		}
+	}
+	return n
+}
+
+// TagNames returns the names of the tags of the task.
+func (t Task) TagNames() []string {
+	names := make([]string, len(t.Tags))
+	for i, tag := range t.Tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// HasTag reports whether the task is tagged with name.
+func (t Task) HasTag(name string) bool {
+	for _, tag := range t.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Status describes the progress of the task in a single word.
+func (t Task) Status() string {
+	switch {
+	case t.CompletedAt.Valid:
+		return "complete"
+	case t.Running():
+		return "running"
+	case t.StartedAt.Valid:
+		return "paused"
+	default:
+		return "todo"
+	}
+}
+
+// StateIn returns which of the columns of the board the task is in. The first
+// column holds new tasks and the last one completed tasks. Tasks that were
+// never moved, or were moved to a column that is no longer configured, are in
+// the first column until they are started and in the second one after.
+func (t Task) StateIn(columns []string) string {
+	last := len(columns) - 1
+	if t.CompletedAt.Valid {
+		return columns[last]
+	}
+	for _, c := range columns[:last] {
+		if c == t.State {
+			return c
+		}
+	}
+	if t.StartedAt.Valid && last > 1 {
+		return columns[1]
+	}
+	return columns[0]
+}
+
+// CycleTime returns how long the task took from when work on it began, by
+// starting its timer or moving it on the board, to when it was completed.
+// It is false for tasks that are not completed.
+func (t Task) CycleTime() (time.Duration, bool) {
+	if !t.CompletedAt.Valid {
+		return 0, false
+	}
+	began := t.CompletedAt.Time
+	if t.StartedAt.Valid && t.StartedAt.Time.Before(began) {
+		began = t.StartedAt.Time
+	}
+	for _, c := range t.StateChanges {
+		if c.ChangedAt.Before(began) {
+			began = c.ChangedAt
+		}
+	}
+	return t.CompletedAt.Time.Sub(began), true
+}
+
+// TimeEntry is a single work session on a task.
+type TimeEntry struct {
+	gorm.Model
+	TaskID uint `gorm:"index"`
+
+	StartedAt time.Time
+	// EndedAt is indexed as the open entries are looked up on every prompt.
+	EndedAt sql.NullTime `gorm:"index"`
+	Note    sql.NullString
+	// Pomodoro is set when the session is a finished pomodoro.
+	Pomodoro bool
+
+	StartSHA []byte
+	EndSHA   []byte
+}
+
+// Duration returns the length of the session, measured up to now if the entry is still open.
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	if e.EndedAt.Valid {
+		return e.EndedAt.Time.Sub(e.StartedAt)
+	}
+	return now.Sub(e.StartedAt)
+}
+
+// TaskCommit is a commit that was made while the task was running, as recorded
+// by the git hooks.
+type TaskCommit struct {
+	gorm.Model
+	TaskID uint   `gorm:"uniqueIndex:idx_task_commit"`
+	SHA    string `gorm:"uniqueIndex:idx_task_commit"`
+
+	Author      string
+	Subject     string
+	CommittedAt time.Time
+}
+
+// StateChange records a task moving between two columns of the board.
+type StateChange struct {
+	gorm.Model
+	TaskID uint `gorm:"index"`
+
+	From      string
+	To        string
+	ChangedAt time.Time
+}
+
+// Tag labels tasks across repos, e.g. "bug" or "meeting".
+type Tag struct {
+	gorm.Model
+	Name string `gorm:"unique"`
+}
+
+// TaskTag links a task to one of its tags.
+type TaskTag struct {
+	TaskID uint `gorm:"primaryKey"`
+	TagID  uint `gorm:"primaryKey"`
+}
+
+// ParseTags splits a comma separated list of names into tags. Names are lower
+// case and use dashes instead of spaces, empty and repeated names are dropped.
+func ParseTags(s string) []Tag {
+	var tags []Tag
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.Join(strings.Fields(part), "-"))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, Tag{Name: name})
+	}
+	return tags
+}
+
+// ShortDuration formats a duration without trailing zero units, e.g. "2h" instead of "2h0m0s".
+func ShortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// SignedDuration formats a duration like ShortDuration but with an explicit sign.
+func SignedDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + ShortDuration(-d)
+	}
+	return "+" + ShortDuration(d)
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC)
+
+func at(hour int) sql.NullTime {
+	return sql.NullTime{Time: day.Add(time.Duration(hour) * time.Hour), Valid: true}
+}
+
+func TestCycleTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		task   Task
+		want   time.Duration
+		wantOK bool
+	}{
+		{
+			name: "not completed",
+			task: Task{StartedAt: at(1)},
+		},
+		{
+			name:   "started and completed",
+			task:   Task{StartedAt: at(1), CompletedAt: at(4)},
+			want:   3 * time.Hour,
+			wantOK: true,
+		},
+		{
+			name:   "completed without being started",
+			task:   Task{CompletedAt: at(4)},
+			wantOK: true,
+		},
+		{
+			name: "moved on the board before it was started",
+			task: Task{
+				StartedAt:    at(2),
+				CompletedAt:  at(4),
+				StateChanges: []StateChange{{ChangedAt: at(3).Time}, {ChangedAt: at(1).Time}},
+			},
+			want:   3 * time.Hour,
+			wantOK: true,
+		},
+		{
+			name:   "started after it was completed",
+			task:   Task{StartedAt: at(5), CompletedAt: at(4)},
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.task.CycleTime()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("got %s, %t, want %s, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestStateIn(t *testing.T) {
+	columns := []string{"todo", "doing", "review", "done"}
+	tests := []struct {
+		name    string
+		task    Task
+		columns []string
+		want    string
+	}{
+		{"new task", Task{}, columns, "todo"},
+		{"started task", Task{StartedAt: at(1)}, columns, "doing"},
+		{"moved task", Task{StartedAt: at(1), State: "review"}, columns, "review"},
+		{"moved back before starting", Task{State: "todo", StartedAt: at(1)}, columns, "todo"},
+		{"completed task", Task{StartedAt: at(1), CompletedAt: at(2), State: "doing"}, columns, "done"},
+		{"moved to the done column without completing", Task{State: "done"}, columns, "todo"},
+		{"moved to a column that was removed", Task{State: "blocked", StartedAt: at(1)}, columns, "doing"},
+		{"started task on a two column board", Task{StartedAt: at(1)}, []string{"open", "closed"}, "open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.StateIn(tt.columns); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
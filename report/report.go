@@ -0,0 +1,167 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// Row compares the estimate of a single task with the time actually tracked on it.
+type Row struct {
+	Task string
+	Repo string
+
+	Estimate time.Duration
+	Actual   time.Duration
+	Complete bool
+	// Pomodoros is the number of pomodoros finished on the task.
+	Pomodoros int
+	// Archived is set when the task or its repo has been archived.
+	Archived bool
+	Tags     []string
+	// CycleTime is how long a completed task took from start to finish.
+	CycleTime time.Duration
+}
+
+// Delta returns how much the actual time overran the estimate, negative if
+// the task finished early.
+func (r Row) Delta() time.Duration { return r.Actual - r.Estimate }
+
+// Ratio returns actual time divided by the estimate.
+func (r Row) Ratio() float64 {
+	if r.Estimate == 0 {
+		return 0
+	}
+	return float64(r.Actual) / float64(r.Estimate)
+}
+
+// Within reports whether the task was done within its estimate.
+func (r Row) Within() bool { return r.Actual <= r.Estimate }
+
+// Aggregate summarizes the estimation accuracy of a group of tasks.
+// Only completed tasks are part of the aggregate, as running tasks have not
+// yet had the chance to meet their estimate.
+type Aggregate struct {
+	Name string
+
+	Tasks     int
+	Estimate  time.Duration
+	Actual    time.Duration
+	overrun   time.Duration
+	withinEst int
+}
+
+func (a *Aggregate) add(r Row) {
+	if !r.Complete || r.Estimate == 0 {
+		return
+	}
+	a.Tasks++
+	a.Estimate += r.Estimate
+	a.Actual += r.Actual
+	a.overrun += r.Delta()
+	if r.Within() {
+		a.withinEst++
+	}
+}
+
+// MeanOverrun returns the average delta between actual and estimated time.
+func (a Aggregate) MeanOverrun() time.Duration {
+	if a.Tasks == 0 {
+		return 0
+	}
+	return a.overrun / time.Duration(a.Tasks)
+}
+
+// WithinShare returns the share of tasks that finished within their estimate.
+func (a Aggregate) WithinShare() float64 {
+	if a.Tasks == 0 {
+		return 0
+	}
+	return float64(a.withinEst) / float64(a.Tasks)
+}
+
+// Ratio returns the total actual time divided by the total estimate.
+func (a Aggregate) Ratio() float64 {
+	if a.Estimate == 0 {
+		return 0
+	}
+	return float64(a.Actual) / float64(a.Estimate)
+}
+
+// Untagged is the name that tasks without tags are grouped under.
+const Untagged = "untagged"
+
+// TagTime is the time tracked on the tasks with a tag, started or completed.
+type TagTime struct {
+	Name   string
+	Tasks  int
+	Actual time.Duration
+}
+
+// Report is the estimate-vs-actual accuracy report of a workspace.
+type Report struct {
+	Rows  []Row
+	Repos []Aggregate
+	Total Aggregate
+	// Tags breaks the tracked time down by tag, most time first. A task with
+	// several tags counts towards each of them.
+	Tags []TagTime
+}
+
+// New builds a report for the workspace, which is expected to have its repos,
+// tasks and time entries loaded. Tasks that have not been started are left out.
+func New(workspace models.Workspace, now time.Time) Report {
+	r := Report{Total: Aggregate{Name: workspace.Name}}
+	for _, repo := range workspace.Repos {
+		agg := Aggregate{Name: repo.Name}
+		for _, task := range repo.Tasks {
+			if !task.StartedAt.Valid {
+				continue
+			}
+			row := Row{
+				Task:      task.Name,
+				Repo:      repo.Name,
+				Estimate:  task.ExpectedDuration,
+				Actual:    task.Tracked(now),
+				Complete:  task.CompletedAt.Valid,
+				Pomodoros: task.Pomodoros(),
+				Archived:  task.DeletedAt.Valid || repo.DeletedAt.Valid,
+				Tags:      task.TagNames(),
+			}
+			row.CycleTime, _ = task.CycleTime()
+			r.Rows = append(r.Rows, row)
+			agg.add(row)
+			r.Total.add(row)
+		}
+		r.Repos = append(r.Repos, agg)
+	}
+	r.Tags = byTag(r.Rows)
+	return r
+}
+
+// byTag sums the time of the rows by tag.
+func byTag(rows []Row) []TagTime {
+	index := make(map[string]int)
+	var tags []TagTime
+	add := func(name string, row Row) {
+		i, ok := index[name]
+		if !ok {
+			i = len(tags)
+			index[name] = i
+			tags = append(tags, TagTime{Name: name})
+		}
+		tags[i].Tasks++
+		tags[i].Actual += row.Actual
+	}
+	for _, row := range rows {
+		if len(row.Tags) == 0 {
+			add(Untagged, row)
+		}
+		for _, name := range row.Tags {
+			add(name, row)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Actual > tags[j].Actual })
+	return tags
+}
@@ -0,0 +1,121 @@
+package report
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+func TestNew(t *testing.T) {
+	now := time.Date(2022, 5, 2, 18, 0, 0, 0, time.UTC)
+	started := sql.NullTime{Time: now.Add(-24 * time.Hour), Valid: true}
+	completed := sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+	// task has tracked hours of time in a single session.
+	task := func(name string, estimate, hours int, done bool, tags ...string) models.Task {
+		t := models.Task{Name: name, StartedAt: started, ExpectedDuration: time.Duration(estimate) * time.Hour}
+		t.TimeEntries = []models.TimeEntry{{
+			StartedAt: started.Time,
+			EndedAt:   sql.NullTime{Time: started.Time.Add(time.Duration(hours) * time.Hour), Valid: true},
+		}}
+		if done {
+			t.CompletedAt = completed
+		}
+		for _, name := range tags {
+			t.Tags = append(t.Tags, models.Tag{Name: name})
+		}
+		return t
+	}
+	workspace := models.Workspace{Name: "work", Repos: []models.Repo{
+		{Name: "api", Tasks: []models.Task{
+			task("under", 4, 2, true, "bug"),
+			task("over", 2, 4, true, "bug", "ui"),
+			task("running", 1, 3, false),
+			task("unestimated", 0, 1, true, "ui"),
+			{Name: "not started", ExpectedDuration: time.Hour},
+		}},
+		{Name: "web", Tasks: []models.Task{
+			task("exact", 3, 3, true),
+		}},
+	}}
+
+	r := New(workspace, now)
+
+	if len(r.Rows) != 5 {
+		t.Errorf("got %d rows, want the 5 started tasks", len(r.Rows))
+	}
+	tests := []struct {
+		name        string
+		agg         Aggregate
+		tasks       int
+		estimate    time.Duration
+		actual      time.Duration
+		meanOverrun time.Duration
+		withinShare float64
+		ratio       float64
+	}{
+		{"api", r.Repos[0], 2, 6 * time.Hour, 6 * time.Hour, 0, 0.5, 1},
+		{"web", r.Repos[1], 1, 3 * time.Hour, 3 * time.Hour, 0, 1, 1},
+		{"total", r.Total, 3, 9 * time.Hour, 9 * time.Hour, 0, 2.0 / 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.agg
+			if a.Tasks != tt.tasks || a.Estimate != tt.estimate || a.Actual != tt.actual {
+				t.Errorf("got %d tasks of %s estimated and %s actual, want %d of %s and %s",
+					a.Tasks, a.Estimate, a.Actual, tt.tasks, tt.estimate, tt.actual)
+			}
+			if a.MeanOverrun() != tt.meanOverrun {
+				t.Errorf("mean overrun is %s, want %s", a.MeanOverrun(), tt.meanOverrun)
+			}
+			if a.WithinShare() != tt.withinShare {
+				t.Errorf("within share is %v, want %v", a.WithinShare(), tt.withinShare)
+			}
+			if a.Ratio() != tt.ratio {
+				t.Errorf("ratio is %v, want %v", a.Ratio(), tt.ratio)
+			}
+		})
+	}
+
+	wantTags := []TagTime{
+		{Name: "bug", Tasks: 2, Actual: 6 * time.Hour},
+		{Name: Untagged, Tasks: 2, Actual: 6 * time.Hour},
+		{Name: "ui", Tasks: 2, Actual: 5 * time.Hour},
+	}
+	if len(r.Tags) != len(wantTags) {
+		t.Fatalf("got tags %+v, want %+v", r.Tags, wantTags)
+	}
+	for i, want := range wantTags {
+		if r.Tags[i] != want {
+			t.Errorf("tag %d is %+v, want %+v", i, r.Tags[i], want)
+		}
+	}
+}
+
+func TestRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		row    Row
+		delta  time.Duration
+		ratio  float64
+		within bool
+	}{
+		{"under the estimate", Row{Estimate: 4 * time.Hour, Actual: 3 * time.Hour}, -time.Hour, 0.75, true},
+		{"over the estimate", Row{Estimate: 2 * time.Hour, Actual: 3 * time.Hour}, time.Hour, 1.5, false},
+		{"no estimate", Row{Actual: time.Hour}, time.Hour, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.row.Delta(); got != tt.delta {
+				t.Errorf("delta is %s, want %s", got, tt.delta)
+			}
+			if got := tt.row.Ratio(); got != tt.ratio {
+				t.Errorf("ratio is %v, want %v", got, tt.ratio)
+			}
+			if got := tt.row.Within(); got != tt.within {
+				t.Errorf("within is %t, want %t", got, tt.within)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// The JSON versions of the models, durations are in seconds like in the export.
+type (
+	workspaceJSON struct {
+		ID          uint      `json:"id"`
+		Name        string    `json:"name"`
+		Description string    `json:"description,omitempty"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+
+	repoJSON struct {
+		ID          uint      `json:"id"`
+		WorkspaceID uint      `json:"workspace_id"`
+		Name        string    `json:"name"`
+		Description string    `json:"description,omitempty"`
+		Remote      string    `json:"remote,omitempty"`
+		Path        string    `json:"path"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+
+	taskJSON struct {
+		ID              uint          `json:"id"`
+		RepoID          uint          `json:"repo_id"`
+		Name            string        `json:"name"`
+		Description     string        `json:"description,omitempty"`
+		Status          string        `json:"status"`
+		Branch          string        `json:"branch,omitempty"`
+		EstimateSeconds int64         `json:"estimate_seconds"`
+		Tags            []string      `json:"tags"`
+		TrackedSeconds  int64         `json:"tracked_seconds"`
+		Pomodoros       int           `json:"pomodoros"`
+		StartedAt       *time.Time    `json:"started_at,omitempty"`
+		CompletedAt     *time.Time    `json:"completed_at,omitempty"`
+		CreatedAt       time.Time     `json:"created_at"`
+		Sessions        []sessionJSON `json:"sessions"`
+	}
+
+	sessionJSON struct {
+		ID              uint       `json:"id"`
+		StartedAt       time.Time  `json:"started_at"`
+		EndedAt         *time.Time `json:"ended_at,omitempty"`
+		DurationSeconds int64      `json:"duration_seconds"`
+		Note            string     `json:"note,omitempty"`
+		Pomodoro        bool       `json:"pomodoro,omitempty"`
+	}
+
+	errorJSON struct {
+		Error string `json:"error"`
+	}
+)
+
+// The bodies of create and update requests. Fields that are left out keep
+// their value when updating.
+type (
+	workspaceInput struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	repoInput struct {
+		WorkspaceID *uint   `json:"workspace_id"`
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Path        *string `json:"path"`
+	}
+
+	taskInput struct {
+		RepoID          *uint     `json:"repo_id"`
+		Name            *string   `json:"name"`
+		Description     *string   `json:"description"`
+		Branch          *string   `json:"branch"`
+		EstimateSeconds *int64    `json:"estimate_seconds"`
+		Tags            *[]string `json:"tags"`
+	}
+
+	// stopInput is the optional body of stopping a timer, At ends the session
+	// at an earlier time.
+	stopInput struct {
+		At *time.Time `json:"at"`
+	}
+)
+
+func newWorkspaceJSON(w models.Workspace) workspaceJSON {
+	return workspaceJSON{ID: w.ID, Name: w.Name, Description: w.Description.String, CreatedAt: w.CreatedAt}
+}
+
+func newRepoJSON(r models.Repo) repoJSON {
+	return repoJSON{
+		ID:          r.ID,
+		WorkspaceID: r.WorkspaceID,
+		Name:        r.Name,
+		Description: r.Description.String,
+		Remote:      strings.TrimSpace(r.Remote),
+		Path:        r.Path,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+func newTaskJSON(t models.Task, now time.Time) taskJSON {
+	tj := taskJSON{
+		ID:              t.ID,
+		RepoID:          t.RepoID,
+		Name:            t.Name,
+		Description:     t.Description.String,
+		Status:          t.Status(),
+		Branch:          t.Branch,
+		EstimateSeconds: int64(t.ExpectedDuration.Seconds()),
+		Tags:            t.TagNames(),
+		TrackedSeconds:  int64(t.Tracked(now).Seconds()),
+		Pomodoros:       t.Pomodoros(),
+		StartedAt:       timePtr(t.StartedAt),
+		CompletedAt:     timePtr(t.CompletedAt),
+		CreatedAt:       t.CreatedAt,
+		Sessions:        []sessionJSON{},
+	}
+	for _, e := range t.TimeEntries {
+		tj.Sessions = append(tj.Sessions, sessionJSON{
+			ID:              e.ID,
+			StartedAt:       e.StartedAt,
+			EndedAt:         timePtr(e.EndedAt),
+			DurationSeconds: int64(e.Duration(now).Seconds()),
+			Note:            e.Note.String,
+			Pomodoro:        e.Pomodoro,
+		})
+	}
+	return tj
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: len(s) > 0}
+}
@@ -0,0 +1,524 @@
+// Package server exposes workspaces, repos, tasks and their timers as a JSON
+// API over HTTP, so that editor plugins and status bars can drive chronograph
+// without the TUI.
+//
+// The API has the following routes, ids are the ids of the models:
+//
+//	GET    /workspaces              list the workspaces
+//	POST   /workspaces              add a workspace
+//	GET    /workspaces/{id}         get a workspace
+//	PATCH  /workspaces/{id}         change a workspace
+//	DELETE /workspaces/{id}         archive a workspace
+//	GET    /repos?workspace={id}    list the repos, of a workspace if given
+//	GET    /tasks?repo={id}         list the tasks, of a repo if given
+//	POST   /tasks/{id}/start        start or resume the timer of a task
+//	POST   /tasks/{id}/stop         pause the timer of a task
+//	POST   /tasks/{id}/complete     complete a task
+//	GET    /running                 list the running tasks
+//
+// Repos and tasks have the same routes as workspaces otherwise. Errors are
+// returned as {"error": "..."} with a fitting status code.
+//
+// The API is not authenticated, so it only serves requests to a loopback host
+// from a local origin, and POST and PATCH requests must be sent as
+// application/json. Web pages can only send those after a preflight, which is
+// never allowed, so other sites cannot drive the timers through the browser.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mellonnen/chronograph/git"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/tracker"
+)
+
+// DefaultAddr is where the server listens unless told otherwise, it is only
+// reachable from the local machine.
+const DefaultAddr = "127.0.0.1:7319"
+
+// Server handles the requests of the API with the same store as the TUI.
+type Server struct {
+	store store.Store
+	// mu serializes requests, as sqlite only allows a single writer at a time.
+	mu sync.Mutex
+}
+
+func New(s store.Store) *Server {
+	return &Server{store: s}
+}
+
+// statusError is an error that is returned with the status code.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e statusError) Error() string { return e.err.Error() }
+
+var (
+	errNotFound         = statusError{http.StatusNotFound, errors.New("no such route")}
+	errMethodNotAllowed = statusError{http.StatusMethodNotAllowed, errors.New("method not allowed")}
+)
+
+func badRequest(format string, args ...interface{}) error {
+	return statusError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var status int
+	var body interface{}
+	err := checkRequest(r)
+	if err == nil {
+		status, body, err = srv.route(r, strings.Split(strings.Trim(r.URL.Path, "/"), "/"))
+	}
+	if err != nil {
+		status, body = statusOf(err), errorJSON{Error: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// checkRequest rejects requests that did not come from the local machine, or
+// that a web page could have sent without a preflight.
+func checkRequest(r *http.Request) error {
+	if !isLoopback(r.Host) {
+		return statusError{http.StatusForbidden, fmt.Errorf("host %q is not local", r.Host)}
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !isLoopback(u.Host) {
+			return statusError{http.StatusForbidden, fmt.Errorf("origin %q is not local", origin)}
+		}
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		if t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || t != "application/json" {
+			return statusError{http.StatusUnsupportedMediaType, errors.New("the content type must be application/json")}
+		}
+	}
+	return nil
+}
+
+// isLoopback reports whether host, with or without a port, is localhost or a
+// loopback address.
+func isLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// statusOf returns the status code that an error is reported with.
+func statusOf(err error) int {
+	var se statusError
+	switch {
+	case errors.As(err, &se):
+		return se.status
+	case errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrDuplicate),
+		errors.Is(err, tracker.ErrCompleted),
+		errors.Is(err, tracker.ErrRunning),
+		errors.Is(err, tracker.ErrNotRunning),
+		errors.Is(err, tracker.ErrNotStarted):
+		return http.StatusConflict
+	case errors.Is(err, tracker.ErrStopTime):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// route dispatches a request by the parts of its path, e.g. ["tasks", "3", "start"].
+func (srv *Server) route(r *http.Request, parts []string) (int, interface{}, error) {
+	if parts[0] == "running" && len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			return 0, nil, errMethodNotAllowed
+		}
+		return srv.running()
+	}
+
+	var id uint
+	if len(parts) > 1 {
+		n, err := strconv.ParseUint(parts[1], 10, 0)
+		if err != nil {
+			return 0, nil, errNotFound
+		}
+		id = uint(n)
+	}
+	switch {
+	case len(parts) == 3 && parts[0] == "tasks":
+		if r.Method != http.MethodPost {
+			return 0, nil, errMethodNotAllowed
+		}
+		return srv.timer(r, id, parts[2])
+	case len(parts) > 2:
+		return 0, nil, errNotFound
+	}
+
+	switch parts[0] {
+	case "workspaces":
+		return srv.workspaces(r, id)
+	case "repos":
+		return srv.repos(r, id)
+	case "tasks":
+		return srv.tasks(r, id)
+	}
+	return 0, nil, errNotFound
+}
+
+func (srv *Server) workspaces(r *http.Request, id uint) (int, interface{}, error) {
+	switch {
+	case id == 0 && r.Method == http.MethodGet:
+		workspaces, err := srv.store.Workspaces()
+		if err != nil {
+			return 0, nil, err
+		}
+		body := make([]workspaceJSON, 0, len(workspaces))
+		for _, w := range workspaces {
+			body = append(body, newWorkspaceJSON(w))
+		}
+		return http.StatusOK, body, nil
+
+	case id == 0 && r.Method == http.MethodPost:
+		var in workspaceInput
+		if err := decode(r.Body, &in); err != nil {
+			return 0, nil, err
+		}
+		if in.Name == nil || *in.Name == "" {
+			return 0, nil, badRequest("a workspace needs a name")
+		}
+		var workspace models.Workspace
+		in.apply(&workspace)
+		if err := srv.store.CreateWorkspace(&workspace); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, newWorkspaceJSON(workspace), nil
+
+	case id == 0:
+		return 0, nil, errMethodNotAllowed
+	}
+
+	workspace, err := srv.store.Workspace(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, newWorkspaceJSON(workspace), nil
+	case http.MethodPatch:
+		var in workspaceInput
+		if err := decode(r.Body, &in); err != nil {
+			return 0, nil, err
+		}
+		in.apply(&workspace)
+		if err := srv.store.UpdateWorkspace(&workspace); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, newWorkspaceJSON(workspace), nil
+	case http.MethodDelete:
+		return http.StatusNoContent, nil, srv.store.ArchiveWorkspace(id)
+	}
+	return 0, nil, errMethodNotAllowed
+}
+
+func (srv *Server) repos(r *http.Request, id uint) (int, interface{}, error) {
+	switch {
+	case id == 0 && r.Method == http.MethodGet:
+		workspaceID, err := queryID(r, "workspace")
+		if err != nil {
+			return 0, nil, err
+		}
+		repos, err := srv.store.Repos(workspaceID)
+		if err != nil {
+			return 0, nil, err
+		}
+		body := make([]repoJSON, 0, len(repos))
+		for _, repo := range repos {
+			body = append(body, newRepoJSON(repo))
+		}
+		return http.StatusOK, body, nil
+
+	case id == 0 && r.Method == http.MethodPost:
+		var in repoInput
+		if err := decode(r.Body, &in); err != nil {
+			return 0, nil, err
+		}
+		if in.Name == nil || *in.Name == "" || in.Path == nil || in.WorkspaceID == nil {
+			return 0, nil, badRequest("a repo needs a name, a path and a workspace_id")
+		}
+		if _, err := srv.store.Workspace(*in.WorkspaceID); err != nil {
+			return 0, nil, err
+		}
+		var repo models.Repo
+		if err := in.apply(&repo); err != nil {
+			return 0, nil, err
+		}
+		if err := srv.store.CreateRepo(&repo); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, newRepoJSON(repo), nil
+
+	case id == 0:
+		return 0, nil, errMethodNotAllowed
+	}
+
+	repo, err := srv.store.Repo(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, newRepoJSON(repo), nil
+	case http.MethodPatch:
+		var in repoInput
+		if err := decode(r.Body, &in); err != nil {
+			return 0, nil, err
+		}
+		if in.WorkspaceID != nil {
+			if _, err := srv.store.Workspace(*in.WorkspaceID); err != nil {
+				return 0, nil, err
+			}
+		}
+		if err := in.apply(&repo); err != nil {
+			return 0, nil, err
+		}
+		if err := srv.store.UpdateRepo(&repo); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, newRepoJSON(repo), nil
+	case http.MethodDelete:
+		return http.StatusNoContent, nil, srv.store.ArchiveRepo(id)
+	}
+	return 0, nil, errMethodNotAllowed
+}
+
+func (srv *Server) tasks(r *http.Request, id uint) (int, interface{}, error) {
+	now := time.Now()
+	switch {
+	case id == 0 && r.Method == http.MethodGet:
+		repoID, err := queryID(r, "repo")
+		if err != nil {
+			return 0, nil, err
+		}
+		tasks, err := srv.store.Tasks(repoID)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, tasksJSON(tasks, now), nil
+
+	case id == 0 && r.Method == http.MethodPost:
+		var in taskInput
+		if err := decode(r.Body, &in); err != nil {
+			return 0, nil, err
+		}
+		if in.Name == nil || *in.Name == "" || in.RepoID == nil {
+			return 0, nil, badRequest("a task needs a name and a repo_id")
+		}
+		if _, err := srv.store.Repo(*in.RepoID); err != nil {
+			return 0, nil, err
+		}
+		var task models.Task
+		in.apply(&task)
+		err := srv.store.Transaction(func(s store.Store) error {
+			if err := s.CreateTask(&task); err != nil {
+				return err
+			}
+			return s.SetTaskTags(&task)
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, newTaskJSON(task, now), nil
+
+	case id == 0:
+		return 0, nil, errMethodNotAllowed
+	}
+
+	task, err := srv.store.Task(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, newTaskJSON(task, now), nil
+	case http.MethodPatch:
+		var in taskInput
+		if err := decode(r.Body, &in); err != nil {
+			return 0, nil, err
+		}
+		if in.RepoID != nil {
+			if _, err := srv.store.Repo(*in.RepoID); err != nil {
+				return 0, nil, err
+			}
+		}
+		in.apply(&task)
+		err := srv.store.Transaction(func(s store.Store) error {
+			if err := s.UpdateTask(&task); err != nil {
+				return err
+			}
+			return s.SetTaskTags(&task)
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, newTaskJSON(task, now), nil
+	case http.MethodDelete:
+		return http.StatusNoContent, nil, srv.store.ArchiveTask(id)
+	}
+	return 0, nil, errMethodNotAllowed
+}
+
+// timer starts, stops or completes the timer of a task.
+func (srv *Server) timer(r *http.Request, id uint, action string) (int, interface{}, error) {
+	task, err := srv.store.Task(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	repo, err := srv.store.Repo(task.RepoID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	at := time.Now()
+	if action == "stop" || action == "complete" {
+		var in stopInput
+		if err := decode(r.Body, &in); err != nil {
+			return 0, nil, err
+		}
+		if in.At != nil {
+			at = *in.At
+		}
+	}
+	switch action {
+	case "start":
+		task, err = tracker.Start(srv.store, task, repo.Path)
+	case "stop":
+		task, err = tracker.PauseAt(srv.store, task, repo.Path, at)
+	case "complete":
+		task, err = tracker.CompleteAt(srv.store, task, repo.Path, at)
+	default:
+		return 0, nil, errNotFound
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newTaskJSON(task, time.Now()), nil
+}
+
+func (srv *Server) running() (int, interface{}, error) {
+	tasks, err := srv.store.RunningTasks()
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, tasksJSON(tasks, time.Now()), nil
+}
+
+func tasksJSON(tasks []models.Task, now time.Time) []taskJSON {
+	body := make([]taskJSON, 0, len(tasks))
+	for _, t := range tasks {
+		body = append(body, newTaskJSON(t, now))
+	}
+	return body
+}
+
+// decode reads a JSON body into v, an empty body leaves v as it is.
+func decode(body io.Reader, v interface{}) error {
+	err := json.NewDecoder(body).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return badRequest("invalid body: %v", err)
+	}
+	return nil
+}
+
+// queryID returns the id in a query parameter, or zero if it is not given.
+func queryID(r *http.Request, name string) (uint, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		return 0, badRequest("invalid %s id %q", name, value)
+	}
+	return uint(id), nil
+}
+
+func (in workspaceInput) apply(w *models.Workspace) {
+	if in.Name != nil {
+		w.Name = *in.Name
+	}
+	if in.Description != nil {
+		w.Description = nullString(*in.Description)
+	}
+}
+
+// apply sets the fields of the repo, finding the root and the remote of the
+// git repo at a new path like the TUI does.
+func (in repoInput) apply(r *models.Repo) error {
+	if in.WorkspaceID != nil {
+		r.WorkspaceID = *in.WorkspaceID
+	}
+	if in.Name != nil {
+		r.Name = *in.Name
+	}
+	if in.Description != nil {
+		r.Description = nullString(*in.Description)
+	}
+	if in.Path != nil {
+		path, err := git.RepoPathFromPath(*in.Path)
+		if err != nil {
+			return badRequest("%s is not a git repo: %v", *in.Path, err)
+		}
+		remote, err := git.RemoteFromPath(path)
+		if err != nil {
+			return fmt.Errorf("getting remote: %w", err)
+		}
+		r.Path = path
+		r.Remote = remote
+	}
+	return nil
+}
+
+func (in taskInput) apply(t *models.Task) {
+	if in.RepoID != nil {
+		t.RepoID = *in.RepoID
+	}
+	if in.Name != nil {
+		t.Name = *in.Name
+	}
+	if in.Description != nil {
+		t.Description = nullString(*in.Description)
+	}
+	if in.Branch != nil {
+		t.Branch = *in.Branch
+	}
+	if in.EstimateSeconds != nil {
+		t.ExpectedDuration = time.Duration(*in.EstimateSeconds) * time.Second
+	}
+	if in.Tags != nil {
+		t.Tags = models.ParseTags(strings.Join(*in.Tags, ","))
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+// newServer serves a store with a workspace, a repo at a temporary git repo
+// and a task.
+func newServer(t *testing.T) *Server {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.email=dev@example.com", "-c", "user.name=Dev", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	s, err := store.Open(filepath.Join(t.TempDir(), "chrono.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace := models.Workspace{Name: "work"}
+	if err := s.CreateWorkspace(&workspace); err != nil {
+		t.Fatal(err)
+	}
+	repo := models.Repo{Name: "chronograph", WorkspaceID: workspace.ID, Path: dir}
+	if err := s.CreateRepo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{Name: "tests", RepoID: repo.ID}
+	if err := s.CreateTask(&task); err != nil {
+		t.Fatal(err)
+	}
+	return New(s)
+}
+
+func TestRoutes(t *testing.T) {
+	srv := newServer(t)
+	// the requests run in order against the same server, the seeded records
+	// have id 1 and the first workspace added has id 2.
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header map[string]string
+		want   int
+		// contains is a part of the response body.
+		contains string
+	}{
+		{"list workspaces", "GET", "/workspaces", "", nil, http.StatusOK, `"name":"work"`},
+		{"get a workspace", "GET", "/workspaces/1", "", nil, http.StatusOK, `"id":1`},
+		{"get a missing workspace", "GET", "/workspaces/9", "", nil, http.StatusNotFound, `"error"`},
+		{"invalid id", "GET", "/workspaces/one", "", nil, http.StatusNotFound, ""},
+		{"unknown route", "GET", "/projects", "", nil, http.StatusNotFound, ""},
+		{"add a workspace", "POST", "/workspaces", `{"name":"home"}`, nil, http.StatusCreated, `"name":"home"`},
+		{"add a workspace with a taken name", "POST", "/workspaces", `{"name":"home"}`, nil, http.StatusConflict, ""},
+		{"add a workspace with an invalid body", "POST", "/workspaces", `{"name":`, nil, http.StatusBadRequest, ""},
+		{"change a workspace", "PATCH", "/workspaces/2", `{"description":"chores"}`, nil, http.StatusOK, `"description":"chores"`},
+		{"archive a workspace", "DELETE", "/workspaces/2", "", nil, http.StatusNoContent, ""},
+		{"get an archived workspace", "GET", "/workspaces/2", "", nil, http.StatusNotFound, ""},
+		{"put a workspace", "PUT", "/workspaces/1", `{}`, nil, http.StatusMethodNotAllowed, ""},
+		{"list the repos of a workspace", "GET", "/repos?workspace=1", "", nil, http.StatusOK, `"name":"chronograph"`},
+		{"list repos of an invalid workspace", "GET", "/repos?workspace=one", "", nil, http.StatusBadRequest, ""},
+		{"add a repo without a path", "POST", "/repos", `{"name":"api","workspace_id":1}`, nil, http.StatusBadRequest, ""},
+		{"add a repo to a missing workspace", "POST", "/repos", `{"name":"api","path":".","workspace_id":9}`, nil, http.StatusNotFound, ""},
+		{"add a task", "POST", "/tasks", `{"name":"docs","repo_id":1,"tags":["Docs"],"estimate_seconds":3600}`, nil, http.StatusCreated, `"tags":["docs"]`},
+		{"add a task without a repo", "POST", "/tasks", `{"name":"docs"}`, nil, http.StatusBadRequest, ""},
+		{"list the tasks of a repo", "GET", "/tasks?repo=1", "", nil, http.StatusOK, `"name":"docs"`},
+		{"start a task", "POST", "/tasks/1/start", "", nil, http.StatusOK, `"status":"running"`},
+		{"start a running task", "POST", "/tasks/1/start", "", nil, http.StatusConflict, ""},
+		{"list the running tasks", "GET", "/running", "", nil, http.StatusOK, `"name":"tests"`},
+		{"stop a task in the future", "POST", "/tasks/1/stop", `{"at":"2999-01-01T00:00:00Z"}`, nil, http.StatusUnprocessableEntity, ""},
+		{"stop a task", "POST", "/tasks/1/stop", "", nil, http.StatusOK, `"status":"paused"`},
+		{"complete a task", "POST", "/tasks/1/complete", "", nil, http.StatusOK, `"status":"complete"`},
+		{"unknown timer action", "POST", "/tasks/1/reset", "", nil, http.StatusNotFound, ""},
+		{"get a timer action", "GET", "/tasks/1/start", "", nil, http.StatusMethodNotAllowed, ""},
+		{"remote host", "GET", "/workspaces", "", map[string]string{"Host": "example.com"}, http.StatusForbidden, ""},
+		{"remote origin", "GET", "/workspaces", "", map[string]string{"Origin": "https://example.com"}, http.StatusForbidden, ""},
+		{"local origin", "GET", "/workspaces", "", map[string]string{"Origin": "http://127.0.0.1:8080"}, http.StatusOK, ""},
+		{"form body", "POST", "/workspaces", "name=home", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, http.StatusUnsupportedMediaType, ""},
+		{"json body with a charset", "POST", "/workspaces", `{"name":"office"}`, map[string]string{"Content-Type": "application/json; charset=utf-8"}, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://localhost:7319"+tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			for k, v := range tt.header {
+				if k == "Host" {
+					r.Host = v
+					continue
+				}
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("body %s does not contain %s", w.Body, tt.contains)
+			}
+		})
+	}
+}
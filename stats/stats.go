@@ -0,0 +1,95 @@
+// Package stats summarizes how the tracked time of a workspace is distributed.
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/report"
+	"github.com/mellonnen/chronograph/timesheet"
+)
+
+// Bar is the time tracked on a single repo or task.
+type Bar struct {
+	Label string
+	Value time.Duration
+}
+
+// Bucket counts the completed tasks whose estimate error falls in a range.
+type Bucket struct {
+	Label string
+	Count int
+}
+
+// bounds split the estimate error, as actual time divided by the estimate
+// minus one, into the ranges named by labels.
+var (
+	bounds = []float64{-0.5, -0.25, 0, 0.25, 0.5, 1}
+	labels = []string{"over 50% early", "25-50% early", "0-25% early", "0-25% late", "25-50% late", "50-100% late", "over 100% late"}
+)
+
+// Stats holds the distribution of tracked time of a workspace.
+type Stats struct {
+	// Repos and Tasks are sorted by the time tracked, most first.
+	Repos []Bar
+	Tasks []Bar
+
+	// Daily holds the time tracked on each day starting at From.
+	From  time.Time
+	Daily []time.Duration
+
+	// Errors is a histogram of how far completed tasks were off their estimate.
+	Errors []Bucket
+}
+
+// New summarizes the workspace, which is expected to have its repos, tasks and
+// time entries loaded. The daily hours cover the last weeks, including today.
+func New(workspace models.Workspace, weeks int, now time.Time) Stats {
+	var s Stats
+	for _, repo := range workspace.Repos {
+		var total time.Duration
+		for _, task := range repo.Tasks {
+			tracked := task.Tracked(now)
+			total += tracked
+			if tracked > 0 {
+				s.Tasks = append(s.Tasks, Bar{Label: task.Name, Value: tracked})
+			}
+		}
+		s.Repos = append(s.Repos, Bar{Label: repo.Name, Value: total})
+	}
+	sortBars(s.Repos)
+	sortBars(s.Tasks)
+
+	days := weeks * 7
+	from := timesheet.StartOfDay(now).AddDate(0, 0, 1-days)
+	sheet := timesheet.New([]models.Workspace{workspace}, from, days, now)
+	s.From = sheet.From
+	s.Daily = sheet.Totals
+
+	s.Errors = make([]Bucket, len(labels))
+	for i, l := range labels {
+		s.Errors[i].Label = l
+	}
+	for _, row := range report.New(workspace, now).Rows {
+		if !row.Complete || row.Estimate == 0 {
+			continue
+		}
+		s.Errors[bucket(row.Ratio()-1)].Count++
+	}
+	return s
+}
+
+// bucket returns the index of the range that the estimate error falls in.
+func bucket(err float64) int {
+	for i, b := range bounds {
+		if err < b {
+			return i
+		}
+	}
+	return len(bounds)
+}
+
+func sortBars(bars []Bar) {
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Value > bars[j].Value })
+}
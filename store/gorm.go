@@ -0,0 +1,471 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// gormStore is a Store backed by GORM.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// busyTimeout is how long a write waits for another process to finish its
+// own before it gives up.
+const busyTimeout = 5 * time.Second
+
+// Open opens the sqlite database at path and migrates the schema.
+//
+// The database is opened in WAL mode so that the TUI, the CLI and the server
+// can read while one of them writes. Transactions take the write lock as they
+// begin, as a transaction that has read cannot wait for the lock of another
+// writer without risking a deadlock.
+func Open(path string) (Store, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	dsn := fmt.Sprintf("%s%s_journal_mode=WAL&_busy_timeout=%d&_txlock=immediate", path, sep, busyTimeout.Milliseconds())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	// names were unique columns before archived records gave them up, which
+	// AutoMigrate replaces with the partial unique indexes by rebuilding the
+	// tables.
+	tables := []interface{}{&models.Workspace{}, &models.Repo{}, &models.Task{}, &models.TimeEntry{}, &models.TaskCommit{}, &models.Tag{}, &models.TaskTag{}, &models.StateChange{}}
+	if err := db.AutoMigrate(tables...); err != nil {
+		return nil, fmt.Errorf("migrating sqlite database: %w", err)
+	}
+	if err := migrateRevision(db, tables); err != nil {
+		return nil, fmt.Errorf("migrating sqlite database: %w", err)
+	}
+	return NewGorm(db), nil
+}
+
+// OpenReadOnly opens the sqlite database at path for reading, without
+// migrating the schema first. It is meant for commands that have to be fast,
+// like the shell prompt.
+func OpenReadOnly(path string) (Store, error) {
+	// the path may carry query parameters for the driver, which are not part
+	// of the file name.
+	file, query, hasQuery := strings.Cut(path, "?")
+	if _, err := os.Stat(file); err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	params := fmt.Sprintf("_query_only=true&_busy_timeout=%d", busyTimeout.Milliseconds())
+	if hasQuery && query != "" {
+		params = query + "&" + params
+	}
+	dsn := file + "?" + params
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	return NewGorm(db), nil
+}
+
+// migrateRevision creates the revision counter, which triggers on the tables
+// count up on every write, whichever process makes it.
+func migrateRevision(db *gorm.DB, tables []interface{}) error {
+	stmts := []string{
+		"CREATE TABLE IF NOT EXISTS revisions (id INTEGER PRIMARY KEY, value INTEGER NOT NULL)",
+		"INSERT OR IGNORE INTO revisions (id, value) VALUES (1, 1)",
+	}
+	for _, model := range tables {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+			stmts = append(stmts, fmt.Sprintf(
+				"CREATE TRIGGER IF NOT EXISTS %[1]s_%[2]s_revision AFTER %[3]s ON %[1]s BEGIN UPDATE revisions SET value = value + 1 WHERE id = 1; END",
+				stmt.Schema.Table, strings.ToLower(event), event))
+		}
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewGorm returns a Store that persists through db, the schema is expected to be migrated.
+func NewGorm(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+// wrap translates GORM errors into the errors of this package.
+func wrap(err error, format string, args ...interface{}) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = ErrNotFound
+	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
+		err = ErrDuplicate
+	}
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err)
+}
+
+func (s *gormStore) Workspaces() ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := s.db.Find(&workspaces).Error
+	return workspaces, wrap(err, "fetching workspaces")
+}
+
+func (s *gormStore) Workspace(id uint) (models.Workspace, error) {
+	var workspace models.Workspace
+	err := s.db.First(&workspace, id).Error
+	return workspace, wrap(err, "fetching workspace")
+}
+
+func (s *gormStore) WorkspaceByName(name string) (models.Workspace, error) {
+	var workspace models.Workspace
+	err := s.db.Where("name = ?", name).First(&workspace).Error
+	return workspace, wrap(err, "fetching workspace %q", name)
+}
+
+func (s *gormStore) CreateWorkspace(workspace *models.Workspace) error {
+	return wrap(s.db.Omit(clause.Associations).Create(workspace).Error, "creating workspace")
+}
+
+func (s *gormStore) UpdateWorkspace(workspace *models.Workspace) error {
+	return wrap(s.db.Omit(clause.Associations).Save(workspace).Error, "updating workspace")
+}
+
+func (s *gormStore) DeleteWorkspace(id uint) error {
+	return s.cascade(&models.Workspace{}, id, "workspace",
+		dependent{&models.TimeEntry{}, "task_id IN (SELECT id FROM tasks WHERE repo_id IN (SELECT id FROM repos WHERE workspace_id = ?))"},
+		dependent{&models.TaskCommit{}, "task_id IN (SELECT id FROM tasks WHERE repo_id IN (SELECT id FROM repos WHERE workspace_id = ?))"},
+		dependent{&models.TaskTag{}, "task_id IN (SELECT id FROM tasks WHERE repo_id IN (SELECT id FROM repos WHERE workspace_id = ?))"},
+		dependent{&models.StateChange{}, "task_id IN (SELECT id FROM tasks WHERE repo_id IN (SELECT id FROM repos WHERE workspace_id = ?))"},
+		dependent{&models.Task{}, "repo_id IN (SELECT id FROM repos WHERE workspace_id = ?)"},
+		dependent{&models.Repo{}, "workspace_id = ?"},
+	)
+}
+
+func (s *gormStore) ArchiveWorkspace(id uint) error {
+	return s.archive(&models.Workspace{}, id, "workspace",
+		dependent{&models.Task{}, "repo_id IN (SELECT id FROM repos WHERE workspace_id = ?)"},
+		dependent{&models.Repo{}, "workspace_id = ?"},
+	)
+}
+
+func (s *gormStore) RestoreWorkspace(id uint) error {
+	return s.restore(&models.Workspace{}, "workspaces", id, "workspace", nil,
+		dependent{&models.Task{}, "repo_id IN (SELECT id FROM repos WHERE workspace_id = ?)"},
+		dependent{&models.Repo{}, "workspace_id = ?"},
+	)
+}
+
+func (s *gormStore) ArchivedWorkspaces() ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := s.db.Unscoped().Where("deleted_at IS NOT NULL").Find(&workspaces).Error
+	return workspaces, wrap(err, "fetching archived workspaces")
+}
+
+func (s *gormStore) Repos(workspaceID uint) ([]models.Repo, error) {
+	var repos []models.Repo
+	query := s.db
+	if workspaceID != 0 {
+		query = query.Where("workspace_id = ?", workspaceID)
+	}
+	err := query.Find(&repos).Error
+	return repos, wrap(err, "fetching repos")
+}
+
+func (s *gormStore) Repo(id uint) (models.Repo, error) {
+	var repo models.Repo
+	err := s.db.First(&repo, id).Error
+	return repo, wrap(err, "fetching repo")
+}
+
+func (s *gormStore) RepoByName(name string) (models.Repo, error) {
+	var repo models.Repo
+	err := s.db.Where("name = ?", name).First(&repo).Error
+	return repo, wrap(err, "fetching repo %q", name)
+}
+
+func (s *gormStore) CreateRepo(repo *models.Repo) error {
+	return wrap(s.db.Omit(clause.Associations).Create(repo).Error, "creating repo")
+}
+
+func (s *gormStore) UpdateRepo(repo *models.Repo) error {
+	return wrap(s.db.Omit(clause.Associations).Save(repo).Error, "updating repo")
+}
+
+func (s *gormStore) DeleteRepo(id uint) error {
+	return s.cascade(&models.Repo{}, id, "repo",
+		dependent{&models.TimeEntry{}, "task_id IN (SELECT id FROM tasks WHERE repo_id = ?)"},
+		dependent{&models.TaskCommit{}, "task_id IN (SELECT id FROM tasks WHERE repo_id = ?)"},
+		dependent{&models.TaskTag{}, "task_id IN (SELECT id FROM tasks WHERE repo_id = ?)"},
+		dependent{&models.StateChange{}, "task_id IN (SELECT id FROM tasks WHERE repo_id = ?)"},
+		dependent{&models.Task{}, "repo_id = ?"},
+	)
+}
+
+func (s *gormStore) ArchiveRepo(id uint) error {
+	return s.archive(&models.Repo{}, id, "repo",
+		dependent{&models.Task{}, "repo_id = ?"},
+	)
+}
+
+func (s *gormStore) RestoreRepo(id uint) error {
+	return s.restore(&models.Repo{}, "repos", id, "repo", &parent{&models.Workspace{}, "workspace_id"},
+		dependent{&models.Task{}, "repo_id = ?"},
+	)
+}
+
+func (s *gormStore) ArchivedRepos(workspaceID uint) ([]models.Repo, error) {
+	var repos []models.Repo
+	query := s.db.Unscoped().Where("deleted_at IS NOT NULL")
+	if workspaceID != 0 {
+		query = query.Where("workspace_id = ?", workspaceID)
+	}
+	err := query.Find(&repos).Error
+	return repos, wrap(err, "fetching archived repos")
+}
+
+// tasks loads the time entries, tags and state changes along with the tasks
+// queried by db.
+func (s *gormStore) tasks(db *gorm.DB) *gorm.DB {
+	return db.Preload("TimeEntries").Preload("Tags").Preload("StateChanges", func(db *gorm.DB) *gorm.DB {
+		return db.Order("changed_at")
+	})
+}
+
+func (s *gormStore) Tasks(repoID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	query := s.tasks(s.db)
+	if repoID != 0 {
+		query = query.Where("repo_id = ?", repoID)
+	}
+	err := query.Find(&tasks).Error
+	return tasks, wrap(err, "fetching tasks")
+}
+
+func (s *gormStore) Task(id uint) (models.Task, error) {
+	var task models.Task
+	err := s.tasks(s.db).First(&task, id).Error
+	return task, wrap(err, "fetching task")
+}
+
+func (s *gormStore) TaskByName(name string) (models.Task, error) {
+	var task models.Task
+	err := s.tasks(s.db).Where("name = ?", name).First(&task).Error
+	return task, wrap(err, "fetching task %q", name)
+}
+
+func (s *gormStore) RunningTasks() ([]models.Task, error) {
+	var tasks []models.Task
+	// time entries are never archived, leaving out the check for it lets
+	// sqlite look up the open entries by the index on ended_at.
+	open := s.db.Unscoped().Model(&models.TimeEntry{}).Select("task_id").Where("ended_at IS NULL")
+	err := s.tasks(s.db).Where("id IN (?)", open).Find(&tasks).Error
+	return tasks, wrap(err, "fetching running tasks")
+}
+
+func (s *gormStore) CreateTask(task *models.Task) error {
+	return wrap(s.db.Omit(clause.Associations).Create(task).Error, "creating task")
+}
+
+func (s *gormStore) UpdateTask(task *models.Task) error {
+	return wrap(s.db.Omit(clause.Associations).Save(task).Error, "updating task")
+}
+
+func (s *gormStore) SetTaskTags(task *models.Task) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range task.Tags {
+			tag := &task.Tags[i]
+			if err := tx.Where("name = ?", tag.Name).FirstOrCreate(tag).Error; err != nil {
+				return wrap(err, "saving tag %q", tag.Name)
+			}
+		}
+		return wrap(tx.Model(task).Association("Tags").Replace(task.Tags), "tagging task")
+	})
+}
+
+func (s *gormStore) DeleteTask(id uint) error {
+	return s.cascade(&models.Task{}, id, "task",
+		dependent{&models.TimeEntry{}, "task_id = ?"},
+		dependent{&models.TaskCommit{}, "task_id = ?"},
+		dependent{&models.TaskTag{}, "task_id = ?"},
+		dependent{&models.StateChange{}, "task_id = ?"},
+	)
+}
+
+func (s *gormStore) ArchiveTask(id uint) error {
+	return s.archive(&models.Task{}, id, "task")
+}
+
+func (s *gormStore) RestoreTask(id uint) error {
+	return s.restore(&models.Task{}, "tasks", id, "task", &parent{&models.Repo{}, "repo_id"})
+}
+
+func (s *gormStore) ArchivedTasks(repoID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	query := s.tasks(s.db.Unscoped()).Where("deleted_at IS NOT NULL")
+	if repoID != 0 {
+		query = query.Where("repo_id = ?", repoID)
+	}
+	err := query.Find(&tasks).Error
+	return tasks, wrap(err, "fetching archived tasks")
+}
+
+func (s *gormStore) TimeEntries(taskID uint) ([]models.TimeEntry, error) {
+	var entries []models.TimeEntry
+	query := s.db
+	if taskID != 0 {
+		query = query.Where("task_id = ?", taskID)
+	}
+	err := query.Find(&entries).Error
+	return entries, wrap(err, "fetching time entries")
+}
+
+func (s *gormStore) CreateTimeEntry(entry *models.TimeEntry) error {
+	return wrap(s.db.Omit(clause.Associations).Create(entry).Error, "creating time entry")
+}
+
+func (s *gormStore) UpdateTimeEntry(entry *models.TimeEntry) error {
+	return wrap(s.db.Omit(clause.Associations).Save(entry).Error, "updating time entry")
+}
+
+func (s *gormStore) DeleteTimeEntry(id uint) error {
+	return s.delete(&models.TimeEntry{}, id, "time entry")
+}
+
+func (s *gormStore) TaskCommits(taskID uint) ([]models.TaskCommit, error) {
+	var commits []models.TaskCommit
+	err := s.db.Where("task_id = ?", taskID).Order("committed_at DESC").Find(&commits).Error
+	return commits, wrap(err, "fetching task commits")
+}
+
+func (s *gormStore) CreateTaskCommit(commit *models.TaskCommit) error {
+	return wrap(s.db.Create(commit).Error, "recording commit")
+}
+
+func (s *gormStore) CreateStateChange(change *models.StateChange) error {
+	return wrap(s.db.Omit(clause.Associations).Create(change).Error, "recording state change")
+}
+
+func (s *gormStore) Revision() (int64, error) {
+	var revision int64
+	err := s.db.Raw("SELECT value FROM revisions WHERE id = 1").Scan(&revision).Error
+	return revision, wrap(err, "fetching revision")
+}
+
+func (s *gormStore) Transaction(fn func(s Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+// delete removes the record with id from the table of model.
+func (s *gormStore) delete(model interface{}, id uint, name string) error {
+	res := s.db.Unscoped().Delete(model, id)
+	if res.Error != nil {
+		return wrap(res.Error, "removing %s", name)
+	}
+	if res.RowsAffected != 1 {
+		return fmt.Errorf("removing %s: %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+// dependent is a table with records that belong to the record being removed,
+// where is a condition on the id of that record.
+type dependent struct {
+	model interface{}
+	where string
+}
+
+// cascade removes the record with id and its dependents in a transaction.
+// sqlite only enforces the OnDelete constraints of the models when foreign keys
+// are enabled, and databases created before the constraints were added lack them,
+// so the dependents are removed explicitly.
+func (s *gormStore) cascade(model interface{}, id uint, name string, dependents ...dependent) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, d := range dependents {
+			if err := tx.Unscoped().Where(d.where, id).Delete(d.model).Error; err != nil {
+				return wrap(err, "removing %s", name)
+			}
+		}
+		return (&gormStore{db: tx}).delete(model, id, name)
+	})
+}
+
+// archive soft deletes the record with id and its dependents that are not
+// archived yet. They share the same deletion time, so that restore can tell
+// them apart from dependents that were archived on their own.
+func (s *gormStore) archive(model interface{}, id uint, name string, dependents ...dependent) error {
+	now := time.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, d := range dependents {
+			if err := tx.Model(d.model).Where(d.where, id).Update("deleted_at", now).Error; err != nil {
+				return wrap(err, "archiving %s", name)
+			}
+		}
+		res := tx.Model(model).Where("id = ?", id).Update("deleted_at", now)
+		if res.Error != nil {
+			return wrap(res.Error, "archiving %s", name)
+		}
+		if res.RowsAffected != 1 {
+			return fmt.Errorf("archiving %s: %w", name, ErrNotFound)
+		}
+		return nil
+	})
+}
+
+// parent is the record a repo or task belongs to, key is the column of model
+// that refers to it.
+type parent struct {
+	model interface{}
+	key   string
+}
+
+// restore undoes archive, table is the table of model.
+func (s *gormStore) restore(model interface{}, table string, id uint, name string, p *parent, dependents ...dependent) error {
+	archivedWith := fmt.Sprintf("deleted_at = (SELECT deleted_at FROM %s WHERE id = ?)", table)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if p != nil {
+			var archived int64
+			err := tx.Unscoped().Model(p.model).
+				Where(fmt.Sprintf("id = (SELECT %s FROM %s WHERE id = ?)", p.key, table), id).
+				Where("deleted_at IS NOT NULL").Count(&archived).Error
+			if err != nil {
+				return wrap(err, "restoring %s", name)
+			}
+			if archived > 0 {
+				return fmt.Errorf("restoring %s: %w", name, ErrArchivedParent)
+			}
+		}
+		for _, d := range dependents {
+			err := tx.Unscoped().Model(d.model).Where(d.where, id).Where(archivedWith, id).Update("deleted_at", nil).Error
+			if err != nil {
+				return wrap(err, "restoring %s", name)
+			}
+		}
+		res := tx.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+		if res.Error != nil {
+			return wrap(res.Error, "restoring %s", name)
+		}
+		if res.RowsAffected != 1 {
+			return fmt.Errorf("restoring %s: %w", name, ErrNotFound)
+		}
+		return nil
+	})
+}
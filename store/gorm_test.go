@@ -0,0 +1,82 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mellonnen/chronograph/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestOpenReleasesArchivedNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chrono.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the workspaces table as it was created while names were unique columns.
+	for _, stmt := range []string{
+		"CREATE TABLE `workspaces` (`id` integer,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text UNIQUE,`description` text,PRIMARY KEY (`id`))",
+		"INSERT INTO `workspaces` (`id`, `name`) VALUES (1, 'work')",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ArchiveWorkspace(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateWorkspace(&models.Workspace{Name: "work"}); err != nil {
+		t.Fatalf("reusing the name of an archived workspace: %v", err)
+	}
+	if err := s.CreateWorkspace(&models.Workspace{Name: "work"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("creating a second workspace named work returned %v, want ErrDuplicate", err)
+	}
+	if err := s.RestoreWorkspace(1); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("restoring the archived workspace returned %v, want ErrDuplicate", err)
+	}
+}
+
+func TestRevisionChangesOnWrites(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "chrono.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := s.Revision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace, _, task := seed(t, s)
+	for _, write := range []struct {
+		name string
+		run  func() error
+	}{
+		{"create", func() error { return s.CreateWorkspace(&models.Workspace{Name: "other"}) }},
+		{"update", func() error {
+			workspace.Description = sql.NullString{String: "changed", Valid: true}
+			return s.UpdateWorkspace(&workspace)
+		}},
+		{"archive", func() error { return s.ArchiveTask(task.ID) }},
+		{"delete", func() error { return s.DeleteTask(task.ID) }},
+	} {
+		if err := write.run(); err != nil {
+			t.Fatalf("%s: %v", write.name, err)
+		}
+		after, err := s.Revision()
+		if err != nil || after == before {
+			t.Errorf("Revision returned %d, %v after %s, was %d", after, err, write.name, before)
+		}
+		before = after
+	}
+}
@@ -0,0 +1,656 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+	"gorm.io/gorm"
+)
+
+// memoryStore is a Store that keeps everything in memory, it is meant for
+// testing the TUI without a database.
+type memoryStore struct {
+	mu     sync.Mutex
+	nextID uint
+
+	workspaces map[uint]models.Workspace
+	repos      map[uint]models.Repo
+	tasks      map[uint]models.Task
+	entries    map[uint]models.TimeEntry
+	commits    map[uint]models.TaskCommit
+	tags       map[uint]models.Tag
+	// taskTags holds the ids of the tags of each task.
+	taskTags map[uint][]uint
+	changes  map[uint]models.StateChange
+}
+
+// NewMemory returns an empty in-memory Store.
+func NewMemory() Store {
+	return &memoryStore{
+		workspaces: make(map[uint]models.Workspace),
+		repos:      make(map[uint]models.Repo),
+		tasks:      make(map[uint]models.Task),
+		entries:    make(map[uint]models.TimeEntry),
+		commits:    make(map[uint]models.TaskCommit),
+		tags:       make(map[uint]models.Tag),
+		taskTags:   make(map[uint][]uint),
+		changes:    make(map[uint]models.StateChange),
+	}
+}
+
+// stamp assigns an id to new records and updates the timestamps.
+func (s *memoryStore) stamp(m *gorm.Model) {
+	now := time.Now()
+	if m.ID == 0 {
+		s.nextID++
+		m.ID = s.nextID
+		m.CreatedAt = now
+	}
+	m.UpdatedAt = now
+}
+
+// sorted returns the values of a table ordered by id.
+func sorted[T any](table map[uint]T, keep func(T) bool) []T {
+	ids := make([]uint, 0, len(table))
+	for id, v := range table {
+		if keep(v) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	values := make([]T, len(ids))
+	for i, id := range ids {
+		values[i] = table[id]
+	}
+	return values
+}
+
+// archivedAt returns when a workspace, repo or task was archived.
+func archivedAt(v models.Listable) gorm.DeletedAt {
+	switch r := v.(type) {
+	case models.Workspace:
+		return r.DeletedAt
+	case models.Repo:
+		return r.DeletedAt
+	case models.Task:
+		return r.DeletedAt
+	}
+	return gorm.DeletedAt{}
+}
+
+// live reports whether v has not been archived.
+func live[T models.Listable](v T) bool {
+	return !archivedAt(v).Valid
+}
+
+// archived reports whether v has been archived.
+func archived[T models.Listable](v T) bool {
+	return archivedAt(v).Valid
+}
+
+// lookup returns the value of a table with id, unless it has been archived.
+func lookup[T models.Listable](table map[uint]T, id uint) (T, bool) {
+	v, ok := table[id]
+	return v, ok && live(v)
+}
+
+// byName returns the first live value of a table with the given name.
+func byName[T models.Listable](table map[uint]T, name string) (T, bool) {
+	for _, v := range sorted(table, func(v T) bool { return live(v) && v.GetName() == name }) {
+		return v, true
+	}
+	var zero T
+	return zero, false
+}
+
+// nameTaken reports whether another record than id uses name. Archived
+// records give up their names, like the partial unique indexes of the gorm store.
+func nameTaken[T models.Listable](table map[uint]T, id uint, name string) bool {
+	for k, v := range table {
+		if k != id && live(v) && v.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryStore) Workspaces() ([]models.Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.workspaces, live[models.Workspace]), nil
+}
+
+func (s *memoryStore) Workspace(id uint) (models.Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := lookup(s.workspaces, id)
+	if !ok {
+		return w, fmt.Errorf("fetching workspace: %w", ErrNotFound)
+	}
+	return w, nil
+}
+
+func (s *memoryStore) WorkspaceByName(name string) (models.Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := byName(s.workspaces, name)
+	if !ok {
+		return w, fmt.Errorf("fetching workspace %q: %w", name, ErrNotFound)
+	}
+	return w, nil
+}
+
+func (s *memoryStore) CreateWorkspace(workspace *models.Workspace) error {
+	workspace.ID = 0
+	return s.saveWorkspace(workspace, "creating")
+}
+
+func (s *memoryStore) UpdateWorkspace(workspace *models.Workspace) error {
+	return s.saveWorkspace(workspace, "updating")
+}
+
+func (s *memoryStore) saveWorkspace(workspace *models.Workspace, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if nameTaken(s.workspaces, workspace.ID, workspace.Name) {
+		return fmt.Errorf("%s workspace: %w", action, ErrDuplicate)
+	}
+	s.stamp(&workspace.Model)
+	w := *workspace
+	w.Repos = nil
+	s.workspaces[w.ID] = w
+	return nil
+}
+
+func (s *memoryStore) DeleteWorkspace(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.workspaces[id]; !ok {
+		return fmt.Errorf("removing workspace: %w", ErrNotFound)
+	}
+	delete(s.workspaces, id)
+	for _, r := range s.repos {
+		if r.WorkspaceID == id {
+			s.deleteRepo(r.ID)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ArchiveWorkspace(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := lookup(s.workspaces, id)
+	if !ok {
+		return fmt.Errorf("archiving workspace: %w", ErrNotFound)
+	}
+	w.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	s.workspaces[id] = w
+	for _, r := range s.repos {
+		if r.WorkspaceID == id && live(r) {
+			s.setRepoArchived(r, w.DeletedAt)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) RestoreWorkspace(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.workspaces[id]
+	if !ok || live(w) {
+		return fmt.Errorf("restoring workspace: %w", ErrNotFound)
+	}
+	taken := nameTaken(s.workspaces, id, w.Name)
+	for _, r := range s.repos {
+		if r.WorkspaceID == id && r.DeletedAt == w.DeletedAt {
+			taken = taken || s.repoNamesTaken(r)
+		}
+	}
+	if taken {
+		return fmt.Errorf("restoring workspace: %w", ErrDuplicate)
+	}
+	for _, r := range s.repos {
+		if r.WorkspaceID == id && r.DeletedAt == w.DeletedAt {
+			s.setRepoArchived(r, gorm.DeletedAt{})
+		}
+	}
+	w.DeletedAt = gorm.DeletedAt{}
+	s.workspaces[id] = w
+	return nil
+}
+
+func (s *memoryStore) ArchivedWorkspaces() ([]models.Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.workspaces, archived[models.Workspace]), nil
+}
+
+func (s *memoryStore) Repos(workspaceID uint) ([]models.Repo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.repos, func(r models.Repo) bool {
+		return live(r) && (workspaceID == 0 || r.WorkspaceID == workspaceID)
+	}), nil
+}
+
+func (s *memoryStore) Repo(id uint) (models.Repo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := lookup(s.repos, id)
+	if !ok {
+		return r, fmt.Errorf("fetching repo: %w", ErrNotFound)
+	}
+	return r, nil
+}
+
+func (s *memoryStore) RepoByName(name string) (models.Repo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := byName(s.repos, name)
+	if !ok {
+		return r, fmt.Errorf("fetching repo %q: %w", name, ErrNotFound)
+	}
+	return r, nil
+}
+
+func (s *memoryStore) CreateRepo(repo *models.Repo) error {
+	repo.ID = 0
+	return s.saveRepo(repo, "creating")
+}
+
+func (s *memoryStore) UpdateRepo(repo *models.Repo) error {
+	return s.saveRepo(repo, "updating")
+}
+
+func (s *memoryStore) saveRepo(repo *models.Repo, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if nameTaken(s.repos, repo.ID, repo.Name) {
+		return fmt.Errorf("%s repo: %w", action, ErrDuplicate)
+	}
+	s.stamp(&repo.Model)
+	r := *repo
+	r.Tasks = nil
+	s.repos[r.ID] = r
+	return nil
+}
+
+func (s *memoryStore) DeleteRepo(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.repos[id]; !ok {
+		return fmt.Errorf("removing repo: %w", ErrNotFound)
+	}
+	s.deleteRepo(id)
+	return nil
+}
+
+// deleteRepo removes a repo with its tasks and time entries, the caller must hold the lock.
+func (s *memoryStore) deleteRepo(id uint) {
+	delete(s.repos, id)
+	for _, t := range s.tasks {
+		if t.RepoID == id {
+			s.deleteTask(t.ID)
+		}
+	}
+}
+
+func (s *memoryStore) ArchiveRepo(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := lookup(s.repos, id)
+	if !ok {
+		return fmt.Errorf("archiving repo: %w", ErrNotFound)
+	}
+	s.setRepoArchived(r, gorm.DeletedAt{Time: time.Now(), Valid: true})
+	return nil
+}
+
+func (s *memoryStore) RestoreRepo(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.repos[id]
+	if !ok || live(r) {
+		return fmt.Errorf("restoring repo: %w", ErrNotFound)
+	}
+	if w, ok := s.workspaces[r.WorkspaceID]; ok && !live(w) {
+		return fmt.Errorf("restoring repo: %w", ErrArchivedParent)
+	}
+	if s.repoNamesTaken(r) {
+		return fmt.Errorf("restoring repo: %w", ErrDuplicate)
+	}
+	s.setRepoArchived(r, gorm.DeletedAt{})
+	return nil
+}
+
+// repoNamesTaken reports whether the name of an archived repo, or of a task
+// that would be restored along with it, has been taken since, the caller must
+// hold the lock.
+func (s *memoryStore) repoNamesTaken(r models.Repo) bool {
+	if nameTaken(s.repos, r.ID, r.Name) {
+		return true
+	}
+	for _, t := range s.tasks {
+		if t.RepoID == r.ID && t.DeletedAt == r.DeletedAt && nameTaken(s.tasks, t.ID, t.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// setRepoArchived archives or restores a repo along with the tasks that share
+// its archive state, the caller must hold the lock.
+func (s *memoryStore) setRepoArchived(r models.Repo, at gorm.DeletedAt) {
+	for _, t := range s.tasks {
+		if t.RepoID == r.ID && t.DeletedAt == r.DeletedAt {
+			t.DeletedAt = at
+			s.tasks[t.ID] = t
+		}
+	}
+	r.DeletedAt = at
+	s.repos[r.ID] = r
+}
+
+func (s *memoryStore) ArchivedRepos(workspaceID uint) ([]models.Repo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.repos, func(r models.Repo) bool {
+		return archived(r) && (workspaceID == 0 || r.WorkspaceID == workspaceID)
+	}), nil
+}
+
+// withEntries attaches the time entries, tags and state changes to a task,
+// the caller must hold the lock.
+func (s *memoryStore) withEntries(t models.Task) models.Task {
+	t.TimeEntries = sorted(s.entries, func(e models.TimeEntry) bool { return e.TaskID == t.ID })
+	t.Tags = nil
+	for _, id := range s.taskTags[t.ID] {
+		t.Tags = append(t.Tags, s.tags[id])
+	}
+	t.StateChanges = sorted(s.changes, func(c models.StateChange) bool { return c.TaskID == t.ID })
+	sort.SliceStable(t.StateChanges, func(i, j int) bool { return t.StateChanges[i].ChangedAt.Before(t.StateChanges[j].ChangedAt) })
+	return t
+}
+
+func (s *memoryStore) Tasks(repoID uint) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks := sorted(s.tasks, func(t models.Task) bool {
+		return live(t) && (repoID == 0 || t.RepoID == repoID)
+	})
+	for i := range tasks {
+		tasks[i] = s.withEntries(tasks[i])
+	}
+	return tasks, nil
+}
+
+func (s *memoryStore) Task(id uint) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := lookup(s.tasks, id)
+	if !ok {
+		return t, fmt.Errorf("fetching task: %w", ErrNotFound)
+	}
+	return s.withEntries(t), nil
+}
+
+func (s *memoryStore) TaskByName(name string) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := byName(s.tasks, name)
+	if !ok {
+		return t, fmt.Errorf("fetching task %q: %w", name, ErrNotFound)
+	}
+	return s.withEntries(t), nil
+}
+
+func (s *memoryStore) RunningTasks() ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var running []models.Task
+	for _, t := range sorted(s.tasks, live[models.Task]) {
+		if t = s.withEntries(t); t.Running() {
+			running = append(running, t)
+		}
+	}
+	return running, nil
+}
+
+func (s *memoryStore) CreateTask(task *models.Task) error {
+	task.ID = 0
+	return s.saveTask(task, "creating")
+}
+
+func (s *memoryStore) UpdateTask(task *models.Task) error {
+	return s.saveTask(task, "updating")
+}
+
+func (s *memoryStore) saveTask(task *models.Task, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if nameTaken(s.tasks, task.ID, task.Name) {
+		return fmt.Errorf("%s task: %w", action, ErrDuplicate)
+	}
+	s.stamp(&task.Model)
+	t := *task
+	t.TimeEntries, t.Tags, t.StateChanges = nil, nil, nil
+	s.tasks[t.ID] = t
+	return nil
+}
+
+func (s *memoryStore) SetTaskTags(task *models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[task.ID]; !ok {
+		return fmt.Errorf("tagging task: %w", ErrNotFound)
+	}
+	ids := make([]uint, len(task.Tags))
+	for i := range task.Tags {
+		tag := &task.Tags[i]
+		if existing, ok := s.tagByName(tag.Name); ok {
+			*tag = existing
+		} else {
+			tag.ID = 0
+			s.stamp(&tag.Model)
+			s.tags[tag.ID] = *tag
+		}
+		ids[i] = tag.ID
+	}
+	s.taskTags[task.ID] = ids
+	return nil
+}
+
+// tagByName returns the tag with name, the caller must hold the lock.
+func (s *memoryStore) tagByName(name string) (models.Tag, bool) {
+	for _, tag := range s.tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return models.Tag{}, false
+}
+
+func (s *memoryStore) DeleteTask(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[id]; !ok {
+		return fmt.Errorf("removing task: %w", ErrNotFound)
+	}
+	s.deleteTask(id)
+	return nil
+}
+
+// deleteTask removes a task with everything that belongs to it, the caller must hold the lock.
+func (s *memoryStore) deleteTask(id uint) {
+	delete(s.tasks, id)
+	delete(s.taskTags, id)
+	for _, c := range s.changes {
+		if c.TaskID == id {
+			delete(s.changes, c.ID)
+		}
+	}
+	for _, e := range s.entries {
+		if e.TaskID == id {
+			delete(s.entries, e.ID)
+		}
+	}
+	for _, c := range s.commits {
+		if c.TaskID == id {
+			delete(s.commits, c.ID)
+		}
+	}
+}
+
+func (s *memoryStore) ArchiveTask(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := lookup(s.tasks, id)
+	if !ok {
+		return fmt.Errorf("archiving task: %w", ErrNotFound)
+	}
+	t.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	s.tasks[id] = t
+	return nil
+}
+
+func (s *memoryStore) RestoreTask(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok || live(t) {
+		return fmt.Errorf("restoring task: %w", ErrNotFound)
+	}
+	if r, ok := s.repos[t.RepoID]; ok && !live(r) {
+		return fmt.Errorf("restoring task: %w", ErrArchivedParent)
+	}
+	if nameTaken(s.tasks, id, t.Name) {
+		return fmt.Errorf("restoring task: %w", ErrDuplicate)
+	}
+	t.DeletedAt = gorm.DeletedAt{}
+	s.tasks[id] = t
+	return nil
+}
+
+func (s *memoryStore) ArchivedTasks(repoID uint) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks := sorted(s.tasks, func(t models.Task) bool {
+		return archived(t) && (repoID == 0 || t.RepoID == repoID)
+	})
+	for i := range tasks {
+		tasks[i] = s.withEntries(tasks[i])
+	}
+	return tasks, nil
+}
+
+func (s *memoryStore) TimeEntries(taskID uint) ([]models.TimeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.entries, func(e models.TimeEntry) bool {
+		return taskID == 0 || e.TaskID == taskID
+	}), nil
+}
+
+func (s *memoryStore) CreateTimeEntry(entry *models.TimeEntry) error {
+	entry.ID = 0
+	return s.UpdateTimeEntry(entry)
+}
+
+func (s *memoryStore) UpdateTimeEntry(entry *models.TimeEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stamp(&entry.Model)
+	s.entries[entry.ID] = *entry
+	return nil
+}
+
+func (s *memoryStore) DeleteTimeEntry(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[id]; !ok {
+		return fmt.Errorf("removing time entry: %w", ErrNotFound)
+	}
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *memoryStore) TaskCommits(taskID uint) ([]models.TaskCommit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	commits := sorted(s.commits, func(c models.TaskCommit) bool { return c.TaskID == taskID })
+	sort.SliceStable(commits, func(i, j int) bool { return commits[i].CommittedAt.After(commits[j].CommittedAt) })
+	return commits, nil
+}
+
+func (s *memoryStore) CreateTaskCommit(commit *models.TaskCommit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.commits {
+		if c.TaskID == commit.TaskID && c.SHA == commit.SHA {
+			return fmt.Errorf("recording commit: %w", ErrDuplicate)
+		}
+	}
+	commit.ID = 0
+	s.stamp(&commit.Model)
+	s.commits[commit.ID] = *commit
+	return nil
+}
+
+func (s *memoryStore) CreateStateChange(change *models.StateChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[change.TaskID]; !ok {
+		return fmt.Errorf("recording state change: %w", ErrNotFound)
+	}
+	change.ID = 0
+	s.stamp(&change.Model)
+	s.changes[change.ID] = *change
+	return nil
+}
+
+// Revision never changes, as nothing but the process that created the store
+// can write to it.
+func (s *memoryStore) Revision() (int64, error) {
+	return 1, nil
+}
+
+// Transaction restores the previous state if fn fails. Unlike a database
+// transaction it does not isolate fn from concurrent writers.
+func (s *memoryStore) Transaction(fn func(s Store) error) error {
+	s.mu.Lock()
+	snapshot := memoryStore{
+		nextID:     s.nextID,
+		workspaces: clone(s.workspaces),
+		repos:      clone(s.repos),
+		tasks:      clone(s.tasks),
+		entries:    clone(s.entries),
+		commits:    clone(s.commits),
+		tags:       clone(s.tags),
+		taskTags:   clone(s.taskTags),
+		changes:    clone(s.changes),
+	}
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.nextID = snapshot.nextID
+		s.workspaces, s.repos, s.tasks, s.entries = snapshot.workspaces, snapshot.repos, snapshot.tasks, snapshot.entries
+		s.commits, s.tags, s.taskTags = snapshot.commits, snapshot.tags, snapshot.taskTags
+		s.changes = snapshot.changes
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func clone[T any](table map[uint]T) map[uint]T {
+	c := make(map[uint]T, len(table))
+	for k, v := range table {
+		c[k] = v
+	}
+	return c
+}
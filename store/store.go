@@ -0,0 +1,81 @@
+// Package store provides access to the persisted workspaces, repos, tasks and time entries.
+package store
+
+import (
+	"errors"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("name already in use")
+	// ErrArchivedParent is returned when restoring a record whose workspace or
+	// repo is archived, as it would not be shown until that is restored too.
+	ErrArchivedParent = errors.New("parent is archived")
+)
+
+// Store is the persistence layer of chronograph.
+//
+// Listing methods that take a parent id list the children of every parent
+// when the id is zero. Tasks are always returned with their time entries.
+//
+// Archiving a record hides it, along with the repos and tasks that belong to it,
+// until it is restored. Archived records are only returned by the Archived
+// methods, and their names can be reused, in which case restoring them fails
+// with ErrDuplicate. Repos and tasks can only be restored on their own while
+// their parent is not archived, otherwise ErrArchivedParent is returned. Deleting a record, archived or not, permanently deletes
+// everything that belongs to it.
+type Store interface {
+	Workspaces() ([]models.Workspace, error)
+	Workspace(id uint) (models.Workspace, error)
+	WorkspaceByName(name string) (models.Workspace, error)
+	CreateWorkspace(workspace *models.Workspace) error
+	UpdateWorkspace(workspace *models.Workspace) error
+	DeleteWorkspace(id uint) error
+	ArchiveWorkspace(id uint) error
+	RestoreWorkspace(id uint) error
+	ArchivedWorkspaces() ([]models.Workspace, error)
+
+	Repos(workspaceID uint) ([]models.Repo, error)
+	Repo(id uint) (models.Repo, error)
+	RepoByName(name string) (models.Repo, error)
+	CreateRepo(repo *models.Repo) error
+	UpdateRepo(repo *models.Repo) error
+	DeleteRepo(id uint) error
+	ArchiveRepo(id uint) error
+	RestoreRepo(id uint) error
+	ArchivedRepos(workspaceID uint) ([]models.Repo, error)
+
+	Tasks(repoID uint) ([]models.Task, error)
+	Task(id uint) (models.Task, error)
+	TaskByName(name string) (models.Task, error)
+	RunningTasks() ([]models.Task, error)
+	CreateTask(task *models.Task) error
+	UpdateTask(task *models.Task) error
+	// SetTaskTags replaces the tags of a task with task.Tags, which are
+	// matched by name. Tags that do not exist yet are created.
+	SetTaskTags(task *models.Task) error
+	DeleteTask(id uint) error
+	ArchiveTask(id uint) error
+	RestoreTask(id uint) error
+	ArchivedTasks(repoID uint) ([]models.Task, error)
+
+	TimeEntries(taskID uint) ([]models.TimeEntry, error)
+	CreateTimeEntry(entry *models.TimeEntry) error
+	UpdateTimeEntry(entry *models.TimeEntry) error
+	DeleteTimeEntry(id uint) error
+
+	TaskCommits(taskID uint) ([]models.TaskCommit, error)
+	CreateTaskCommit(commit *models.TaskCommit) error
+
+	CreateStateChange(change *models.StateChange) error
+
+	// Revision returns a number that changes whenever anything is written,
+	// including by other processes that share the database.
+	Revision() (int64, error)
+
+	// Transaction runs fn atomically, if fn returns an error none of its
+	// changes are persisted.
+	Transaction(fn func(s Store) error) error
+}
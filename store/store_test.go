@@ -0,0 +1,225 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// stores returns an empty store of every kind, which the contract is checked against.
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "chrono.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemory(), "gorm": db}
+}
+
+// seed creates a workspace with a repo that has a task.
+func seed(t *testing.T, s Store) (models.Workspace, models.Repo, models.Task) {
+	t.Helper()
+	workspace := models.Workspace{Name: "work"}
+	if err := s.CreateWorkspace(&workspace); err != nil {
+		t.Fatal(err)
+	}
+	repo := models.Repo{Name: "chronograph", WorkspaceID: workspace.ID}
+	if err := s.CreateRepo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{Name: "tests", RepoID: repo.ID}
+	if err := s.CreateTask(&task); err != nil {
+		t.Fatal(err)
+	}
+	return workspace, repo, task
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func wantErr(t *testing.T, what string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%s returned %v, want %v", what, err, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Store)
+	}{
+		{"create and fetch", func(t *testing.T, s Store) {
+			workspace, repo, task := seed(t, s)
+			if w, err := s.WorkspaceByName("work"); err != nil || w.ID != workspace.ID {
+				t.Errorf("WorkspaceByName returned %v, %v", w.ID, err)
+			}
+			if r, err := s.Repo(repo.ID); err != nil || r.Name != "chronograph" || r.WorkspaceID != workspace.ID {
+				t.Errorf("Repo returned %+v, %v", r, err)
+			}
+			if got, err := s.TaskByName("tests"); err != nil || got.ID != task.ID || got.RepoID != repo.ID {
+				t.Errorf("TaskByName returned %+v, %v", got, err)
+			}
+			if tasks, err := s.Tasks(repo.ID); err != nil || len(tasks) != 1 {
+				t.Errorf("Tasks returned %d tasks, %v", len(tasks), err)
+			}
+			if repos, err := s.Repos(0); err != nil || len(repos) != 1 {
+				t.Errorf("Repos of every workspace returned %d repos, %v", len(repos), err)
+			}
+		}},
+		{"missing records", func(t *testing.T, s Store) {
+			_, err := s.Workspace(42)
+			wantErr(t, "Workspace", err, ErrNotFound)
+			_, err = s.RepoByName("missing")
+			wantErr(t, "RepoByName", err, ErrNotFound)
+			_, err = s.Task(42)
+			wantErr(t, "Task", err, ErrNotFound)
+			wantErr(t, "ArchiveTask", s.ArchiveTask(42), ErrNotFound)
+			wantErr(t, "RestoreRepo", s.RestoreRepo(42), ErrNotFound)
+		}},
+		{"duplicate names", func(t *testing.T, s Store) {
+			_, repo, _ := seed(t, s)
+			wantErr(t, "CreateWorkspace", s.CreateWorkspace(&models.Workspace{Name: "work"}), ErrDuplicate)
+			wantErr(t, "CreateTask", s.CreateTask(&models.Task{Name: "tests", RepoID: repo.ID}), ErrDuplicate)
+			other := models.Repo{Name: "other"}
+			check(t, s.CreateRepo(&other))
+			other.Name = "chronograph"
+			wantErr(t, "UpdateRepo", s.UpdateRepo(&other), ErrDuplicate)
+		}},
+		{"archived names are given up", func(t *testing.T, s Store) {
+			_, repo, task := seed(t, s)
+			check(t, s.ArchiveTask(task.ID))
+			check(t, s.CreateTask(&models.Task{Name: "tests", RepoID: repo.ID}))
+			wantErr(t, "RestoreTask", s.RestoreTask(task.ID), ErrDuplicate)
+			if archived, err := s.ArchivedTasks(repo.ID); err != nil || len(archived) != 1 {
+				t.Errorf("ArchivedTasks returned %d tasks, %v", len(archived), err)
+			}
+		}},
+		{"archiving hides the children", func(t *testing.T, s Store) {
+			workspace, repo, task := seed(t, s)
+			check(t, s.ArchiveWorkspace(workspace.ID))
+			if repos, err := s.Repos(workspace.ID); err != nil || len(repos) != 0 {
+				t.Errorf("Repos returned %d repos, %v", len(repos), err)
+			}
+			_, err := s.Task(task.ID)
+			wantErr(t, "Task", err, ErrNotFound)
+			if archived, err := s.ArchivedRepos(workspace.ID); err != nil || len(archived) != 1 {
+				t.Errorf("ArchivedRepos returned %d repos, %v", len(archived), err)
+			}
+
+			check(t, s.RestoreWorkspace(workspace.ID))
+			if tasks, err := s.Tasks(repo.ID); err != nil || len(tasks) != 1 {
+				t.Errorf("Tasks returned %d tasks after restoring, %v", len(tasks), err)
+			}
+		}},
+		{"restoring leaves children archived on their own", func(t *testing.T, s Store) {
+			_, repo, task := seed(t, s)
+			check(t, s.ArchiveTask(task.ID))
+			check(t, s.ArchiveRepo(repo.ID))
+			check(t, s.RestoreRepo(repo.ID))
+			_, err := s.Task(task.ID)
+			wantErr(t, "Task", err, ErrNotFound)
+		}},
+		{"restoring needs the parent", func(t *testing.T, s Store) {
+			workspace, repo, task := seed(t, s)
+			check(t, s.ArchiveTask(task.ID))
+			check(t, s.ArchiveWorkspace(workspace.ID))
+			wantErr(t, "RestoreRepo", s.RestoreRepo(repo.ID), ErrArchivedParent)
+			wantErr(t, "RestoreTask", s.RestoreTask(task.ID), ErrArchivedParent)
+			check(t, s.RestoreWorkspace(workspace.ID))
+			check(t, s.RestoreTask(task.ID))
+		}},
+		{"deleting removes the children", func(t *testing.T, s Store) {
+			workspace, repo, task := seed(t, s)
+			entry := models.TimeEntry{TaskID: task.ID, StartedAt: time.Now()}
+			check(t, s.CreateTimeEntry(&entry))
+			check(t, s.DeleteWorkspace(workspace.ID))
+			_, err := s.Repo(repo.ID)
+			wantErr(t, "Repo", err, ErrNotFound)
+			if entries, err := s.TimeEntries(task.ID); err != nil || len(entries) != 0 {
+				t.Errorf("TimeEntries returned %d entries, %v", len(entries), err)
+			}
+		}},
+		{"running tasks", func(t *testing.T, s Store) {
+			_, _, task := seed(t, s)
+			entry := models.TimeEntry{TaskID: task.ID, StartedAt: time.Now().Add(-time.Hour)}
+			check(t, s.CreateTimeEntry(&entry))
+			running, err := s.RunningTasks()
+			if err != nil || len(running) != 1 || len(running[0].TimeEntries) != 1 {
+				t.Fatalf("RunningTasks returned %d tasks, %v", len(running), err)
+			}
+			entry.EndedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			check(t, s.UpdateTimeEntry(&entry))
+			if running, err := s.RunningTasks(); err != nil || len(running) != 0 {
+				t.Errorf("RunningTasks returned %d tasks after pausing, %v", len(running), err)
+			}
+		}},
+		{"tags", func(t *testing.T, s Store) {
+			_, _, task := seed(t, s)
+			for _, tags := range []string{"bug, ui", "ui", ""} {
+				task.Tags = models.ParseTags(tags)
+				check(t, s.SetTaskTags(&task))
+				got, err := s.Task(task.ID)
+				check(t, err)
+				names := got.TagNames()
+				sort.Strings(names)
+				want := task.TagNames()
+				sort.Strings(want)
+				if len(names) != len(want) || (len(names) > 0 && names[0] != want[0]) {
+					t.Errorf("tags %q were stored as %v", tags, names)
+				}
+			}
+		}},
+		{"transactions roll back", func(t *testing.T, s Store) {
+			failed := errors.New("failed")
+			err := s.Transaction(func(tx Store) error {
+				check(t, tx.CreateWorkspace(&models.Workspace{Name: "rolled back"}))
+				return failed
+			})
+			wantErr(t, "Transaction", err, failed)
+			_, err = s.WorkspaceByName("rolled back")
+			wantErr(t, "WorkspaceByName", err, ErrNotFound)
+		}},
+	}
+	for _, tt := range tests {
+		for kind, s := range stores(t) {
+			s := s
+			t.Run(kind+"/"+tt.name, func(t *testing.T) { tt.test(t, s) })
+		}
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chrono.db")
+	db, err := Open(path)
+	check(t, err)
+	_, _, task := seed(t, db)
+
+	for _, dsn := range []string{path, path + "?_foreign_keys=on", path + "?"} {
+		t.Run(dsn, func(t *testing.T) {
+			s, err := OpenReadOnly(dsn)
+			check(t, err)
+			got, err := s.Task(task.ID)
+			check(t, err)
+			if got.Name != task.Name {
+				t.Errorf("read task %q, want %q", got.Name, task.Name)
+			}
+			if err := s.CreateWorkspace(&models.Workspace{Name: "home"}); err == nil {
+				t.Error("a read-only store was written to")
+			}
+		})
+	}
+	_, err = OpenReadOnly(filepath.Join(t.TempDir(), "missing.db") + "?_foreign_keys=on")
+	if err == nil {
+		t.Error("opened a database that does not exist")
+	}
+}
@@ -0,0 +1,95 @@
+// Package timesheet lays out the tracked time of tasks per day.
+package timesheet
+
+import (
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// Row holds the time logged on a single task on each day of the sheet.
+type Row struct {
+	Task      models.Task
+	Repo      string
+	Workspace string
+
+	Days  []time.Duration
+	Total time.Duration
+}
+
+// Sheet is a grid of days by tasks, starting at the midnight of From.
+type Sheet struct {
+	From time.Time
+	Rows []Row
+
+	// Totals holds the time logged on all tasks on each day.
+	Totals []time.Duration
+	Total  time.Duration
+}
+
+// Day returns the midnight that starts day i of the sheet.
+func (s Sheet) Day(i int) time.Time {
+	return s.From.AddDate(0, 0, i)
+}
+
+// Days returns the number of days in the sheet.
+func (s Sheet) Days() int { return len(s.Totals) }
+
+// StartOfDay returns the midnight that starts the day of t, in the location of t.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfWeek returns the midnight that starts the week of t, weeks start on Monday.
+func StartOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return StartOfDay(t).AddDate(0, 0, -offset)
+}
+
+// New lays out the time entries of the tasks in the workspaces over the days
+// starting at from. Entries that span midnight are split between the days,
+// open entries are measured up to now. Tasks that are not completed get a row
+// even without time, so that time can be logged on them.
+func New(workspaces []models.Workspace, from time.Time, days int, now time.Time) Sheet {
+	from = StartOfDay(from)
+	s := Sheet{From: from, Totals: make([]time.Duration, days)}
+	for _, ws := range workspaces {
+		for _, repo := range ws.Repos {
+			for _, task := range repo.Tasks {
+				row := Row{Task: task, Repo: repo.Name, Workspace: ws.Name, Days: make([]time.Duration, days)}
+				for _, e := range task.TimeEntries {
+					end := now
+					if e.EndedAt.Valid {
+						end = e.EndedAt.Time
+					}
+					for i := range row.Days {
+						d := overlap(e.StartedAt, end, s.Day(i), s.Day(i+1))
+						row.Days[i] += d
+						row.Total += d
+						s.Totals[i] += d
+					}
+				}
+				s.Total += row.Total
+				if row.Total > 0 || !task.CompletedAt.Valid {
+					s.Rows = append(s.Rows, row)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// overlap returns how much of the interval from start to end falls between from and to.
+func overlap(start, end, from, to time.Time) time.Duration {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !start.Before(end) {
+		return 0
+	}
+	return end.Sub(start)
+}
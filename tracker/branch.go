@@ -0,0 +1,93 @@
+package tracker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/mellonnen/chronograph/git"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+// invalidRef matches runs of characters that git does not allow in branch names.
+var invalidRef = regexp.MustCompile(`[\x00-\x20\x7f~^:?*\[\\]+|\.\.+|@\{`)
+
+// BranchName renders the branch template for the task. Spaces become dashes and
+// characters that are not allowed in branch names are dropped.
+func BranchName(tmpl string, task models.Task) (string, error) {
+	t, err := template.New("branch").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing branch template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, task); err != nil {
+		return "", fmt.Errorf("rendering branch template: %w", err)
+	}
+	name := strings.Join(strings.Fields(b.String()), "-")
+	name = invalidRef.ReplaceAllString(name, "")
+	name = strings.Trim(name, "-/.")
+	name = strings.TrimSuffix(name, ".lock")
+	if err := git.CheckBranchName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// CheckoutBranch checks out branch in the repo, creating it if it does not
+// exist, and records it as the branch of the task.
+func CheckoutBranch(s store.Store, task models.Task, repoPath, branch string) (models.Task, error) {
+	if repoPath == "" {
+		return task, ErrNoPath
+	}
+	exists, err := git.BranchExists(repoPath, branch)
+	if err != nil {
+		return task, err
+	}
+	if err := git.Checkout(repoPath, branch, !exists); err != nil {
+		return task, err
+	}
+	task.Branch = branch
+	if err := s.UpdateTask(&task); err != nil {
+		return task, fmt.Errorf("saving branch of %q: %w", task.Name, err)
+	}
+	return task, nil
+}
+
+// BranchStatus describes whether the branch of the task has been merged into
+// the default branch of the repo, it is empty if the task has no branch.
+func BranchStatus(task models.Task, repoPath string) (string, error) {
+	if task.Branch == "" || repoPath == "" {
+		return "", nil
+	}
+	exists, err := git.BranchExists(repoPath, task.Branch)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "deleted", nil
+	}
+	base, err := git.DefaultBranch(repoPath)
+	if err != nil {
+		return "", err
+	}
+	if base == task.Branch || strings.HasSuffix(base, "/"+task.Branch) {
+		return "default branch", nil
+	}
+	tip, err := git.RevParse(repoPath, task.Branch)
+	if err != nil {
+		return "", err
+	}
+	if tip == string(task.StartSHA) {
+		return "no commits", nil
+	}
+	merged, err := git.IsAncestor(repoPath, tip, base)
+	if err != nil {
+		return "", err
+	}
+	if merged {
+		return "merged", nil
+	}
+	return "not merged", nil
+}
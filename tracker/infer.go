@@ -0,0 +1,200 @@
+package tracker
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mellonnen/chronograph/git"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+var ErrNoTasks = errors.New("repo has no tasks to add sessions to")
+
+// Proposal is a work session inferred from commits, it becomes a time entry of
+// Task once accepted.
+type Proposal struct {
+	Task  models.Task
+	Start time.Time
+	End   time.Time
+	// Commits are the commits of the session, oldest first.
+	Commits []git.Commit
+}
+
+// Duration returns the length of the proposed session.
+func (p Proposal) Duration() time.Duration { return p.End.Sub(p.Start) }
+
+// ClusterCommits groups commits that are at most gap apart into sessions. A
+// session starts leadIn before its first commit, but never before the previous
+// session ended, and ends at its last commit. The commits are sorted by time
+// first, as git need not list them in that order.
+func ClusterCommits(commits []git.Commit, gap, leadIn time.Duration) []Proposal {
+	sorted := append([]git.Commit(nil), commits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	var proposals []Proposal
+	for _, c := range sorted {
+		if n := len(proposals); n > 0 && c.Time.Sub(proposals[n-1].End) <= gap {
+			proposals[n-1].End = c.Time
+			proposals[n-1].Commits = append(proposals[n-1].Commits, c)
+			continue
+		}
+		start := c.Time.Add(-leadIn)
+		if n := len(proposals); n > 0 && start.Before(proposals[n-1].End) {
+			start = proposals[n-1].End
+		}
+		proposals = append(proposals, Proposal{Start: start, End: c.Time, Commits: []git.Commit{c}})
+	}
+	return proposals
+}
+
+// Infer proposes sessions for the commits the user made in the repo between
+// from and to, leaving out the commits made while any task of the repo was
+// tracked. A proposal goes to the task its commits are linked to by the git
+// hooks, else to the task whose branch contains them, else to the open task
+// that was worked on last.
+func Infer(s store.Store, repo models.Repo, from, to time.Time, gap, leadIn time.Duration) ([]Proposal, error) {
+	tasks, err := s.Tasks(repo.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, ErrNoTasks
+	}
+	if repo.Path == "" {
+		return nil, ErrNoPath
+	}
+	author, err := git.UserEmail(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := git.CommitsBetween(repo.Path, author, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	linked := make(map[string]models.Task)
+	untracked := commits[:0]
+	for _, t := range tasks {
+		links, err := s.TaskCommits(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			linked[l.SHA] = t
+		}
+	}
+	for _, c := range commits {
+		if !tracked(tasks, c.Time, now) {
+			untracked = append(untracked, c)
+		}
+	}
+
+	proposals := ClusterCommits(untracked, gap, leadIn)
+	fallback := lastWorkedOn(tasks)
+	for i, p := range proposals {
+		proposals[i].Task = fallback
+		if t, ok := linkedTask(p.Commits, linked); ok {
+			proposals[i].Task = t
+			continue
+		}
+		if t, ok := branchTask(repo.Path, p.Commits, tasks); ok {
+			proposals[i].Task = t
+		}
+	}
+	return proposals, nil
+}
+
+// Accept adds the proposal as a closed time entry of its task, which is marked
+// as started if it was not already.
+func Accept(s store.Store, p Proposal, repoPath string) (models.Task, error) {
+	entry := models.TimeEntry{
+		StartedAt: p.Start,
+		EndedAt:   sql.NullTime{Time: p.End, Valid: true},
+		Note:      sql.NullString{String: fmt.Sprintf("Inferred from %d commits", len(p.Commits)), Valid: true},
+	}
+	if len(p.Commits) > 0 {
+		entry.EndSHA = []byte(p.Commits[len(p.Commits)-1].SHA)
+		// the first commit of a repo has no parent to start from.
+		if sha, err := git.RevParse(repoPath, p.Commits[0].SHA+"^"); err == nil {
+			entry.StartSHA = []byte(sha)
+		}
+	}
+	task, err := addEntry(s, p.Task, entry)
+	if err != nil {
+		return task, fmt.Errorf("accepting session: %w", err)
+	}
+	return task, nil
+}
+
+// tracked reports whether any of the tasks was being tracked at t.
+func tracked(tasks []models.Task, t, now time.Time) bool {
+	for _, task := range tasks {
+		for _, e := range task.TimeEntries {
+			end := now
+			if e.EndedAt.Valid {
+				end = e.EndedAt.Time
+			}
+			if !t.Before(e.StartedAt) && !t.After(end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// linkedTask returns the task that the most commits are linked to.
+func linkedTask(commits []git.Commit, linked map[string]models.Task) (models.Task, bool) {
+	counts := make(map[uint]int)
+	var best models.Task
+	for _, c := range commits {
+		t, ok := linked[c.SHA]
+		if !ok {
+			continue
+		}
+		counts[t.ID]++
+		if counts[t.ID] > counts[best.ID] {
+			best = t
+		}
+	}
+	return best, len(counts) > 0
+}
+
+// branchTask returns the task whose branch contains the last of the commits.
+func branchTask(repoPath string, commits []git.Commit, tasks []models.Task) (models.Task, bool) {
+	branches, err := git.BranchesContaining(repoPath, commits[len(commits)-1].SHA)
+	if err != nil {
+		return models.Task{}, false
+	}
+	for _, b := range branches {
+		for _, t := range tasks {
+			if t.Branch != "" && t.Branch == b {
+				return t, true
+			}
+		}
+	}
+	return models.Task{}, false
+}
+
+// lastWorkedOn returns the open task with the latest time entry, or the first
+// task if none has been worked on.
+func lastWorkedOn(tasks []models.Task) models.Task {
+	last := tasks[0]
+	var latest time.Time
+	for _, t := range tasks {
+		if t.CompletedAt.Valid {
+			continue
+		}
+		for _, e := range t.TimeEntries {
+			if e.StartedAt.After(latest) {
+				latest = e.StartedAt
+				last = t
+			}
+		}
+	}
+	return last
+}
@@ -0,0 +1,214 @@
+package tracker
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mellonnen/chronograph/git"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+func TestClusterCommits(t *testing.T) {
+	base := time.Date(2022, 5, 2, 9, 0, 0, 0, time.UTC)
+	at := func(sha string, minutes int) git.Commit {
+		return git.Commit{SHA: sha, Time: base.Add(time.Duration(minutes) * time.Minute)}
+	}
+	type session struct {
+		start, end int
+		shas       []string
+	}
+	tests := []struct {
+		name    string
+		commits []git.Commit
+		// leadIn is in minutes, the gap is an hour.
+		leadIn int
+		want   []session
+	}{
+		{
+			name:   "no commits",
+			leadIn: 30,
+		},
+		{
+			name:    "single commit",
+			commits: []git.Commit{at("a", 60)},
+			leadIn:  30,
+			want:    []session{{30, 60, []string{"a"}}},
+		},
+		{
+			name:    "commits within the gap",
+			commits: []git.Commit{at("a", 60), at("b", 100), at("c", 160)},
+			leadIn:  30,
+			want:    []session{{30, 160, []string{"a", "b", "c"}}},
+		},
+		{
+			name:    "commits further apart than the gap",
+			commits: []git.Commit{at("a", 60), at("b", 200)},
+			leadIn:  30,
+			want:    []session{{30, 60, []string{"a"}}, {170, 200, []string{"b"}}},
+		},
+		{
+			name:    "lead-in cut short by the previous session",
+			commits: []git.Commit{at("a", 120), at("b", 190)},
+			leadIn:  90,
+			want:    []session{{30, 120, []string{"a"}}, {120, 190, []string{"b"}}},
+		},
+		{
+			name:    "out of order",
+			commits: []git.Commit{at("c", 300), at("a", 60), at("d", 320), at("b", 90)},
+			leadIn:  30,
+			want:    []session{{30, 90, []string{"a", "b"}}, {270, 320, []string{"c", "d"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClusterCommits(tt.commits, time.Hour, time.Duration(tt.leadIn)*time.Minute)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d sessions, want %d", len(got), len(tt.want))
+			}
+			for i, w := range tt.want {
+				p := got[i]
+				if start := base.Add(time.Duration(w.start) * time.Minute); !p.Start.Equal(start) {
+					t.Errorf("session %d starts at %s, want %s", i, p.Start, start)
+				}
+				if end := base.Add(time.Duration(w.end) * time.Minute); !p.End.Equal(end) {
+					t.Errorf("session %d ends at %s, want %s", i, p.End, end)
+				}
+				if p.Duration() < 0 {
+					t.Errorf("session %d has a negative duration", i)
+				}
+				var shas []string
+				for _, c := range p.Commits {
+					shas = append(shas, c.SHA)
+				}
+				if !equal(shas, w.shas) {
+					t.Errorf("session %d has commits %v, want %v", i, shas, w.shas)
+				}
+			}
+		})
+	}
+}
+
+func TestInfer(t *testing.T) {
+	dir := newRepo(t)
+	day := time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	// the day before is outside the range, the feature branch starts from it.
+	commit(t, dir, day.Add(-time.Hour), day.Add(-time.Hour))
+	gitRun(t, dir, nil, "branch", "feature")
+	first := commit(t, dir, at(9, 0), at(9, 0))
+	linked := commit(t, dir, at(17, 0), at(17, 0))
+	commit(t, dir, at(20, 0), at(20, 0))
+	// rebased, so it was committed long after it was authored.
+	rebased := commit(t, dir, at(9, 30), at(21, 0))
+	gitRun(t, dir, nil, "checkout", "-q", "feature")
+	feature := commit(t, dir, at(13, 0), at(13, 0))
+
+	s := store.NewMemory()
+	repo := models.Repo{Name: "repo", Path: dir}
+	if err := s.CreateRepo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	tasks := map[string]*models.Task{
+		"branch":  {Name: "branch", Branch: "feature"},
+		"linked":  {Name: "linked"},
+		"tracked": {Name: "tracked"},
+	}
+	for _, name := range []string{"branch", "linked", "tracked"} {
+		tasks[name].RepoID = repo.ID
+		if err := s.CreateTask(tasks[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CreateTaskCommit(&models.TaskCommit{TaskID: tasks["linked"].ID, SHA: linked}); err != nil {
+		t.Fatal(err)
+	}
+	tracked := models.TimeEntry{TaskID: tasks["tracked"].ID, StartedAt: at(19, 30), EndedAt: sql.NullTime{Time: at(20, 30), Valid: true}}
+	if err := s.CreateTimeEntry(&tracked); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Infer(s, repo, day, day.Add(24*time.Hour), time.Hour, 30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		start, end time.Time
+		task       string
+		shas       []string
+	}{
+		{"out of order commits of the task worked on last", at(8, 30), at(9, 30), "tracked", []string{first, rebased}},
+		{"commit on the branch of a task", at(12, 30), at(13, 0), "branch", []string{feature}},
+		{"commit linked by the hooks", at(16, 30), at(17, 0), "linked", []string{linked}},
+	}
+	if len(got) != len(tests) {
+		t.Fatalf("got %d proposals, want %d", len(got), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := got[i]
+			if !p.Start.Equal(tt.start) || !p.End.Equal(tt.end) {
+				t.Errorf("proposal runs from %s to %s, want %s to %s", p.Start, p.End, tt.start, tt.end)
+			}
+			if p.Task.ID != tasks[tt.task].ID {
+				t.Errorf("proposal goes to %q, want %q", p.Task.Name, tt.task)
+			}
+			var shas []string
+			for _, c := range p.Commits {
+				shas = append(shas, c.SHA)
+			}
+			if !equal(shas, tt.shas) {
+				t.Errorf("proposal has commits %v, want %v", shas, tt.shas)
+			}
+		})
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestInferByAuthorDate(t *testing.T) {
+	dir := newRepo(t)
+	from := time.Now().Add(-96 * time.Hour).Truncate(time.Second)
+	to := from.Add(4 * time.Hour)
+	inRange := commit(t, dir, from.Add(time.Hour), from.Add(time.Hour))
+	rebased := commit(t, dir, from.Add(2*time.Hour), to.Add(48*time.Hour))
+	// cherry-picked from work that was done before the range.
+	commit(t, dir, from.Add(-2*time.Hour), from.Add(3*time.Hour))
+
+	s := store.NewMemory()
+	repo := models.Repo{Name: "repo", Path: dir}
+	if err := s.CreateRepo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateTask(&models.Task{Name: "task", RepoID: repo.ID}); err != nil {
+		t.Fatal(err)
+	}
+	proposals, err := Infer(s, repo, from, to, time.Hour, 30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range proposals {
+		for _, c := range p.Commits {
+			got = append(got, c.SHA)
+		}
+	}
+	if len(got) != 2 || got[0] != inRange || got[1] != rebased {
+		t.Errorf("got commits %v, want %s and the rebased %s", got, inRange, rebased)
+	}
+}
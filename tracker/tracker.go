@@ -0,0 +1,496 @@
+// Package tracker contains the time tracking logic shared by the TUI and the
+// command line interface.
+package tracker
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mellonnen/chronograph/git"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+var (
+	ErrCompleted  = errors.New("task is already completed")
+	ErrRunning    = errors.New("task is already running")
+	ErrNotRunning = errors.New("task is not running")
+	ErrNotStarted = errors.New("task has not been started")
+	ErrNoPomodoro = errors.New("task has no running pomodoro")
+	ErrStopTime   = errors.New("cannot stop the session at that time")
+	ErrNoColumn   = errors.New("no such column on the board")
+	// ErrNoPath is returned by the git features for repos without a path,
+	// e.g. the ones created by importing from other time trackers.
+	ErrNoPath = errors.New("repo has no path")
+)
+
+// Start opens a new time entry on the task, recording the current HEAD of the repo.
+// The first entry also marks the task as started.
+func Start(s store.Store, task models.Task, repoPath string) (models.Task, error) {
+	return start(s, task, repoPath, false)
+}
+
+// StartPomodoro opens a new time entry on the task like Start, which is tagged
+// as a pomodoro.
+func StartPomodoro(s store.Store, task models.Task, repoPath string) (models.Task, error) {
+	return start(s, task, repoPath, true)
+}
+
+// Switch starts the task like Start after pausing the tasks that are running,
+// so that only one timer runs at a time. The branch is checked out first if
+// it is given. Nothing changes if the task cannot be started, and the tasks
+// that were paused are returned along with the started task.
+func Switch(s store.Store, task models.Task, repoPath, branch string) (models.Task, []models.Task, error) {
+	return switchTo(s, task, repoPath, branch, false)
+}
+
+// SwitchPomodoro starts a pomodoro on the task like Switch.
+func SwitchPomodoro(s store.Store, task models.Task, repoPath, branch string) (models.Task, []models.Task, error) {
+	return switchTo(s, task, repoPath, branch, true)
+}
+
+func switchTo(s store.Store, task models.Task, repoPath, branch string, pomodoro bool) (models.Task, []models.Task, error) {
+	if task.CompletedAt.Valid {
+		return task, nil, ErrCompleted
+	}
+	if task.Running() {
+		return task, nil, ErrRunning
+	}
+	var paused []models.Task
+	err := s.Transaction(func(tx store.Store) error {
+		running, err := tx.RunningTasks()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, t := range running {
+			repo, err := tx.Repo(t.RepoID)
+			if err != nil {
+				return err
+			}
+			if t, err = PauseAt(tx, t, repo.Path, now); err != nil {
+				return err
+			}
+			paused = append(paused, t)
+		}
+		if branch != "" {
+			if task, err = CheckoutBranch(tx, task, repoPath, branch); err != nil {
+				return err
+			}
+		}
+		task, err = start(tx, task, repoPath, pomodoro)
+		return err
+	})
+	if err != nil {
+		return task, nil, err
+	}
+	return task, paused, nil
+}
+
+func start(s store.Store, task models.Task, repoPath string, pomodoro bool) (models.Task, error) {
+	if task.CompletedAt.Valid {
+		return task, ErrCompleted
+	}
+	if task.Running() {
+		return task, ErrRunning
+	}
+	sha := headSHA(repoPath)
+	now := time.Now()
+	err := s.Transaction(func(tx store.Store) error {
+		entry := models.TimeEntry{TaskID: task.ID, StartedAt: now, StartSHA: sha, Pomodoro: pomodoro}
+		if err := tx.CreateTimeEntry(&entry); err != nil {
+			return err
+		}
+		if task.StartedAt.Valid {
+			return nil
+		}
+		task.StartedAt = sql.NullTime{Time: now, Valid: true}
+		task.StartSHA = sha
+		return tx.UpdateTask(&task)
+	})
+	if err != nil {
+		return task, fmt.Errorf("starting task: %w", err)
+	}
+	return s.Task(task.ID)
+}
+
+// Pause closes the open time entry of the task, recording the current HEAD of the repo.
+func Pause(s store.Store, task models.Task, repoPath string) (models.Task, error) {
+	return PauseAt(s, task, repoPath, time.Now())
+}
+
+// PauseAt closes the open time entry of the task at an earlier time, e.g. when
+// the timer was left running, recording the HEAD of the repo at that time.
+func PauseAt(s store.Store, task models.Task, repoPath string, at time.Time) (models.Task, error) {
+	entry := task.ActiveEntry()
+	if entry == nil {
+		return task, ErrNotRunning
+	}
+	if err := checkStop(*entry, at); err != nil {
+		return task, err
+	}
+	entry.EndedAt = sql.NullTime{Time: at, Valid: true}
+	entry.EndSHA = shaBefore(repoPath, at)
+	// a pomodoro that is paused is interrupted, so it is logged as a plain session.
+	entry.Pomodoro = false
+	if err := s.UpdateTimeEntry(entry); err != nil {
+		return task, fmt.Errorf("pausing task: %w", err)
+	}
+	return s.Task(task.ID)
+}
+
+// FinishPomodoro closes the open pomodoro of the task at the time it was due,
+// recording the current HEAD of the repo.
+func FinishPomodoro(s store.Store, task models.Task, repoPath string, at time.Time) (models.Task, error) {
+	entry := task.ActiveEntry()
+	if entry == nil || !entry.Pomodoro {
+		return task, ErrNoPomodoro
+	}
+	entry.EndedAt = sql.NullTime{Time: at, Valid: true}
+	entry.EndSHA = headSHA(repoPath)
+	if err := s.UpdateTimeEntry(entry); err != nil {
+		return task, fmt.Errorf("finishing pomodoro: %w", err)
+	}
+	return s.Task(task.ID)
+}
+
+// Complete closes any open time entry and records the completion time and the
+// current HEAD of the repo on the task.
+func Complete(s store.Store, task models.Task, repoPath string) (models.Task, error) {
+	return CompleteAt(s, task, repoPath, time.Now())
+}
+
+// CompleteAt completes the task like Complete, but at an earlier time.
+func CompleteAt(s store.Store, task models.Task, repoPath string, at time.Time) (models.Task, error) {
+	if task.CompletedAt.Valid {
+		return task, ErrCompleted
+	}
+	if !task.StartedAt.Valid {
+		return task, ErrNotStarted
+	}
+	if entry := task.ActiveEntry(); entry != nil {
+		if err := checkStop(*entry, at); err != nil {
+			return task, err
+		}
+	}
+	sha := shaBefore(repoPath, at)
+	now := sql.NullTime{Time: at, Valid: true}
+	err := s.Transaction(func(tx store.Store) error {
+		if entry := task.ActiveEntry(); entry != nil {
+			entry.EndedAt = now
+			entry.EndSHA = sha
+			entry.Pomodoro = false
+			if err := tx.UpdateTimeEntry(entry); err != nil {
+				return err
+			}
+		}
+		task.CompletedAt = now
+		task.EndSHA = sha
+		return tx.UpdateTask(&task)
+	})
+	if err != nil {
+		return task, fmt.Errorf("completing task: %w", err)
+	}
+	return s.Task(task.ID)
+}
+
+// Move puts the task in another of the columns of the board and records when
+// it moved. Moving to the last column completes the task like Complete, even
+// if it was never started, and moving out of it reopens the task.
+func Move(s store.Store, task models.Task, repoPath string, columns []string, to string) (models.Task, error) {
+	known := false
+	for _, c := range columns {
+		known = known || c == to
+	}
+	if !known {
+		return task, fmt.Errorf("%w: %q", ErrNoColumn, to)
+	}
+	from := task.StateIn(columns)
+	if from == to {
+		return task, nil
+	}
+	done := columns[len(columns)-1]
+	var sha []byte
+	if to == done {
+		sha = headSHA(repoPath)
+	}
+	now := time.Now()
+	err := s.Transaction(func(tx store.Store) error {
+		switch {
+		case to == done:
+			if entry := task.ActiveEntry(); entry != nil {
+				entry.EndedAt = sql.NullTime{Time: now, Valid: true}
+				entry.EndSHA = sha
+				entry.Pomodoro = false
+				if err := tx.UpdateTimeEntry(entry); err != nil {
+					return err
+				}
+			}
+			task.CompletedAt = sql.NullTime{Time: now, Valid: true}
+			task.EndSHA = sha
+		case from == done:
+			task.CompletedAt = sql.NullTime{}
+			task.EndSHA = nil
+		}
+		task.State = to
+		if err := tx.UpdateTask(&task); err != nil {
+			return err
+		}
+		return tx.CreateStateChange(&models.StateChange{TaskID: task.ID, From: from, To: to, ChangedAt: now})
+	})
+	if err != nil {
+		return task, fmt.Errorf("moving task to %s: %w", to, err)
+	}
+	return s.Task(task.ID)
+}
+
+// headSHA returns the HEAD of the repo at repoPath. The SHAs only link the
+// commits to the sessions, so time is tracked without them when the repo has
+// no path, no commits yet or is gone, rather than not at all.
+func headSHA(repoPath string) []byte {
+	if repoPath == "" {
+		return nil
+	}
+	sha, err := git.HeadSHA(repoPath)
+	if err != nil {
+		return nil
+	}
+	return []byte(sha)
+}
+
+// shaBefore returns the latest commit on HEAD before at like headSHA does.
+func shaBefore(repoPath string, at time.Time) []byte {
+	if repoPath == "" {
+		return nil
+	}
+	sha, err := git.SHABefore(repoPath, at)
+	if err != nil {
+		return nil
+	}
+	return []byte(sha)
+}
+
+// checkStop reports an error if the open entry cannot be closed at the time.
+func checkStop(entry models.TimeEntry, at time.Time) error {
+	if at.After(time.Now()) {
+		return fmt.Errorf("%w: %s is in the future", ErrStopTime, at.Format("2006-01-02 15:04"))
+	}
+	if at.Before(entry.StartedAt) {
+		return fmt.Errorf("%w: %s is before it started at %s", ErrStopTime, at.Format("2006-01-02 15:04"), entry.StartedAt.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// TrimIdle ends the open session of the task when the user went idle, and
+// starts a new session now that they are back. The idle time is logged as a
+// session of its own when keepIdle is set, and dropped otherwise.
+func TrimIdle(s store.Store, task models.Task, repoPath string, idleSince time.Time, keepIdle bool) (models.Task, error) {
+	entry := task.ActiveEntry()
+	if entry == nil {
+		return task, ErrNotRunning
+	}
+	now := time.Now()
+	if idleSince.Before(entry.StartedAt) {
+		idleSince = entry.StartedAt
+	}
+	endSHA := shaBefore(repoPath, idleSince)
+	head := headSHA(repoPath)
+	err := s.Transaction(func(tx store.Store) error {
+		// nothing was done in a session that was idle from the start.
+		if idleSince.Equal(entry.StartedAt) {
+			if err := tx.DeleteTimeEntry(entry.ID); err != nil {
+				return err
+			}
+		} else {
+			entry.EndedAt = sql.NullTime{Time: idleSince, Valid: true}
+			entry.EndSHA = endSHA
+			entry.Pomodoro = false
+			if err := tx.UpdateTimeEntry(entry); err != nil {
+				return err
+			}
+		}
+		if keepIdle {
+			idle := models.TimeEntry{
+				TaskID:    task.ID,
+				StartedAt: idleSince,
+				EndedAt:   sql.NullTime{Time: now, Valid: true},
+				Note:      sql.NullString{String: "Idle", Valid: true},
+				StartSHA:  endSHA,
+				EndSHA:    head,
+			}
+			if err := tx.CreateTimeEntry(&idle); err != nil {
+				return err
+			}
+		}
+		next := models.TimeEntry{TaskID: task.ID, StartedAt: now, StartSHA: head}
+		return tx.CreateTimeEntry(&next)
+	})
+	if err != nil {
+		return task, fmt.Errorf("trimming idle time: %w", err)
+	}
+	return s.Task(task.ID)
+}
+
+// Log adds a closed time entry from start to end to the task, for time that
+// was not tracked with the timer.
+func Log(s store.Store, task models.Task, start, end time.Time, note string) (models.Task, error) {
+	if !start.Before(end) {
+		return task, fmt.Errorf("logging time: %s is not before %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	entry := models.TimeEntry{
+		StartedAt: start,
+		EndedAt:   sql.NullTime{Time: end, Valid: true},
+		Note:      sql.NullString{String: note, Valid: len(note) > 0},
+	}
+	task, err := addEntry(s, task, entry)
+	if err != nil {
+		return task, fmt.Errorf("logging time: %w", err)
+	}
+	return task, nil
+}
+
+// addEntry creates the entry on the task, and marks the task as started at the
+// start of the entry if it was not started before.
+func addEntry(s store.Store, task models.Task, entry models.TimeEntry) (models.Task, error) {
+	entry.TaskID = task.ID
+	err := s.Transaction(func(tx store.Store) error {
+		if err := tx.CreateTimeEntry(&entry); err != nil {
+			return err
+		}
+		if task.StartedAt.Valid && !entry.StartedAt.Before(task.StartedAt.Time) {
+			return nil
+		}
+		task.StartedAt = sql.NullTime{Time: entry.StartedAt, Valid: true}
+		if len(entry.StartSHA) > 0 {
+			task.StartSHA = entry.StartSHA
+		}
+		return tx.UpdateTask(&task)
+	})
+	if err != nil {
+		return task, err
+	}
+	return s.Task(task.ID)
+}
+
+// Annotate sets the note of a time entry.
+func Annotate(s store.Store, entry models.TimeEntry, note string) error {
+	entry.Note = sql.NullString{String: note, Valid: len(note) > 0}
+	if err := s.UpdateTimeEntry(&entry); err != nil {
+		return fmt.Errorf("annotating time entry: %w", err)
+	}
+	return nil
+}
+
+// WorkspaceTree fetches all repos, tasks and time entries of a workspace,
+// including the archived repos and tasks when archived is set.
+func WorkspaceTree(s store.Store, workspace models.Workspace, archived bool) (models.Workspace, error) {
+	repos, err := s.Repos(workspace.ID)
+	if err != nil {
+		return workspace, err
+	}
+	if archived {
+		archivedRepos, err := s.ArchivedRepos(workspace.ID)
+		if err != nil {
+			return workspace, err
+		}
+		repos = append(repos, archivedRepos...)
+	}
+	for i := range repos {
+		if repos[i].Tasks, err = s.Tasks(repos[i].ID); err != nil {
+			return workspace, err
+		}
+		if !archived {
+			continue
+		}
+		tasks, err := s.ArchivedTasks(repos[i].ID)
+		if err != nil {
+			return workspace, err
+		}
+		repos[i].Tasks = append(repos[i].Tasks, tasks...)
+	}
+	workspace.Repos = repos
+	return workspace, nil
+}
+
+// Children counts the records that belong to a workspace, repo or task.
+type Children struct {
+	Repos       int
+	Tasks       int
+	TimeEntries int
+}
+
+// Empty reports whether there are no children.
+func (c Children) Empty() bool {
+	return c == Children{}
+}
+
+func (c Children) String() string {
+	parts := make([]string, 0, 3)
+	for _, p := range []struct {
+		n              int
+		single, plural string
+	}{
+		{c.Repos, "repo", "repos"},
+		{c.Tasks, "task", "tasks"},
+		{c.TimeEntries, "time entry", "time entries"},
+	} {
+		switch p.n {
+		case 0:
+		case 1:
+			parts = append(parts, fmt.Sprintf("1 %s", p.single))
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", p.n, p.plural))
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "nothing"
+	case 1:
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// CountChildren counts the records that are removed along with a workspace, repo
+// or task, archived or not.
+func CountChildren(s store.Store, resource models.Listable) (Children, error) {
+	var c Children
+	switch r := resource.(type) {
+	case models.Workspace:
+		tree, err := WorkspaceTree(s, r, true)
+		if err != nil {
+			return c, err
+		}
+		c.Repos = len(tree.Repos)
+		for _, repo := range tree.Repos {
+			c.Tasks += len(repo.Tasks)
+			for _, t := range repo.Tasks {
+				c.TimeEntries += len(t.TimeEntries)
+			}
+		}
+	case models.Repo:
+		tasks, err := s.Tasks(r.ID)
+		if err != nil {
+			return c, err
+		}
+		archived, err := s.ArchivedTasks(r.ID)
+		if err != nil {
+			return c, err
+		}
+		tasks = append(tasks, archived...)
+		c.Tasks = len(tasks)
+		for _, t := range tasks {
+			c.TimeEntries += len(t.TimeEntries)
+		}
+	case models.Task:
+		entries, err := s.TimeEntries(r.ID)
+		if err != nil {
+			return c, err
+		}
+		c.TimeEntries = len(entries)
+	}
+	return c, nil
+}
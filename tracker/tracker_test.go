@@ -0,0 +1,249 @@
+package tracker
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+)
+
+// newRepo initializes an empty git repo in a temporary directory.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitRun(t, dir, nil, "init", "-q", "-b", "main")
+	gitRun(t, dir, nil, "config", "user.email", "dev@example.com")
+	gitRun(t, dir, nil, "config", "user.name", "Dev")
+	return dir
+}
+
+// commit makes an empty commit authored and committed at the times, and
+// returns its SHA.
+func commit(t *testing.T, dir string, authored, committed time.Time) string {
+	t.Helper()
+	env := []string{
+		"GIT_AUTHOR_DATE=" + authored.Format(time.RFC3339),
+		"GIT_COMMITTER_DATE=" + committed.Format(time.RFC3339),
+	}
+	gitRun(t, dir, env, "commit", "-q", "--allow-empty", "-m", "work")
+	return gitRun(t, dir, nil, "rev-parse", "HEAD")
+}
+
+func gitRun(t *testing.T, dir string, env []string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestTrimIdle(t *testing.T) {
+	dir := newRepo(t)
+	now := time.Now()
+	commit(t, dir, now.Add(-3*time.Hour), now.Add(-3*time.Hour))
+
+	type entry struct {
+		// start and end are in minutes before now, an end of zero is still open.
+		start, end int
+		note       string
+	}
+	tests := []struct {
+		name      string
+		started   int
+		idleSince int
+		keepIdle  bool
+		want      []entry
+	}{
+		{
+			name:      "idle time dropped",
+			started:   120,
+			idleSince: 60,
+			want:      []entry{{120, 60, ""}, {0, 0, ""}},
+		},
+		{
+			name:      "idle time kept",
+			started:   120,
+			idleSince: 60,
+			keepIdle:  true,
+			want:      []entry{{120, 60, ""}, {60, 0, "Idle"}, {0, 0, ""}},
+		},
+		{
+			name:      "idle from the start",
+			started:   60,
+			idleSince: 60,
+			want:      []entry{{0, 0, ""}},
+		},
+		{
+			name:      "idle before the start",
+			started:   60,
+			idleSince: 90,
+			keepIdle:  true,
+			want:      []entry{{60, 0, "Idle"}, {0, 0, ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store.NewMemory()
+			repo := models.Repo{Name: "repo", Path: dir}
+			if err := s.CreateRepo(&repo); err != nil {
+				t.Fatal(err)
+			}
+			task := models.Task{Name: "task", RepoID: repo.ID}
+			if err := s.CreateTask(&task); err != nil {
+				t.Fatal(err)
+			}
+			started := now.Add(-time.Duration(tt.started) * time.Minute)
+			if err := s.CreateTimeEntry(&models.TimeEntry{TaskID: task.ID, StartedAt: started}); err != nil {
+				t.Fatal(err)
+			}
+			task, err := s.Task(task.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task, err = TrimIdle(s, task, dir, now.Add(-time.Duration(tt.idleSince)*time.Minute), tt.keepIdle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(task.TimeEntries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d", len(task.TimeEntries), len(tt.want))
+			}
+			for i, w := range tt.want {
+				e := task.TimeEntries[i]
+				if w.start > 0 && !e.StartedAt.Equal(now.Add(-time.Duration(w.start)*time.Minute)) {
+					t.Errorf("entry %d starts at %s, want %d minutes ago", i, e.StartedAt, w.start)
+				}
+				if w.end > 0 && !e.EndedAt.Time.Equal(now.Add(-time.Duration(w.end)*time.Minute)) {
+					t.Errorf("entry %d ends at %s, want %d minutes ago", i, e.EndedAt.Time, w.end)
+				}
+				if w.end == 0 && i == len(tt.want)-1 && e.EndedAt.Valid {
+					t.Errorf("entry %d is closed, want it open", i)
+				}
+				if e.Note.String != w.note {
+					t.Errorf("entry %d has note %q, want %q", i, e.Note.String, w.note)
+				}
+			}
+		})
+	}
+}
+
+func TestTrimIdleNotRunning(t *testing.T) {
+	task := models.Task{TimeEntries: []models.TimeEntry{{
+		StartedAt: time.Now().Add(-time.Hour),
+		EndedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+	}}}
+	if _, err := TrimIdle(store.NewMemory(), task, "", time.Now(), false); err != ErrNotRunning {
+		t.Errorf("got %v, want ErrNotRunning", err)
+	}
+}
+
+func TestTrackingWithoutCommits(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		// the tests run inside the repo of chronograph, whose HEAD must not be recorded.
+		{"repo without a path", func(t *testing.T) string { return "" }},
+		{"repo without commits", newRepo},
+		{"repo that is gone", func(t *testing.T) string { return filepath.Join(t.TempDir(), "gone") }},
+	}
+	columns := []string{"todo", "doing", "done"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path(t)
+			s := store.NewMemory()
+			task := models.Task{Name: "task"}
+			if err := s.CreateTask(&task); err != nil {
+				t.Fatal(err)
+			}
+
+			steps := []struct {
+				name string
+				run  func() (models.Task, error)
+			}{
+				{"start", func() (models.Task, error) { return StartPomodoro(s, task, path) }},
+				{"finish pomodoro", func() (models.Task, error) { return FinishPomodoro(s, task, path, time.Now()) }},
+				{"resume", func() (models.Task, error) { return Start(s, task, path) }},
+				{"trim idle", func() (models.Task, error) {
+					return TrimIdle(s, task, path, time.Now().Add(-time.Millisecond), true)
+				}},
+				{"pause", func() (models.Task, error) { return PauseAt(s, task, path, time.Now()) }},
+				{"complete", func() (models.Task, error) { return CompleteAt(s, task, path, time.Now()) }},
+				{"reopen", func() (models.Task, error) { return Move(s, task, path, columns, "doing") }},
+				{"move to done", func() (models.Task, error) { return Move(s, task, path, columns, "done") }},
+			}
+			for _, step := range steps {
+				var err error
+				if task, err = step.run(); err != nil {
+					t.Fatalf("%s: %v", step.name, err)
+				}
+			}
+
+			if !task.StartedAt.Valid || !task.CompletedAt.Valid {
+				t.Errorf("task was not started and completed: %+v", task)
+			}
+			if len(task.StartSHA) != 0 || len(task.EndSHA) != 0 {
+				t.Errorf("task has SHAs %q..%q, want none", task.StartSHA, task.EndSHA)
+			}
+			for i, e := range task.TimeEntries {
+				if len(e.StartSHA) != 0 || len(e.EndSHA) != 0 {
+					t.Errorf("entry %d has SHAs %q..%q, want none", i, e.StartSHA, e.EndSHA)
+				}
+			}
+		})
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	s := store.NewMemory()
+	repo := models.Repo{Name: "repo"}
+	if err := s.CreateRepo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	running := models.Task{Name: "running", RepoID: repo.ID}
+	next := models.Task{Name: "next", RepoID: repo.ID}
+	done := models.Task{Name: "done", RepoID: repo.ID}
+	for _, task := range []*models.Task{&running, &next, &done} {
+		if err := s.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var err error
+	if running, err = Start(s, running, ""); err != nil {
+		t.Fatal(err)
+	}
+	if done, err = Move(s, done, "", []string{"todo", "done"}, "done"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Switch(s, done, "", ""); !errors.Is(err, ErrCompleted) {
+		t.Errorf("switching to a completed task returned %v, want %v", err, ErrCompleted)
+	}
+	if tasks, err := s.RunningTasks(); err != nil || len(tasks) != 1 || tasks[0].ID != running.ID {
+		t.Errorf("a failed switch left %d tasks running, %v", len(tasks), err)
+	}
+
+	next, paused, err := Switch(s, next, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paused) != 1 || paused[0].ID != running.ID || paused[0].Running() {
+		t.Errorf("switching paused %+v, want %s", paused, running.Name)
+	}
+	if tasks, err := s.RunningTasks(); err != nil || len(tasks) != 1 || tasks[0].ID != next.ID {
+		t.Errorf("switching left %d tasks running, %v", len(tasks), err)
+	}
+}
@@ -3,15 +3,32 @@ package ui
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/export"
 	"github.com/mellonnen/chronograph/models"
-	"gorm.io/gorm"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/timesheet"
+	"github.com/mellonnen/chronograph/tracker"
 )
 
 var appStyle = lipgloss.NewStyle().Padding(1, 2)
 
+// headerHeight is the number of lines taken by the breadcrumbs above every view.
+const headerHeight = 2
+
+// frameSize returns the horizontal and vertical space that is not available to views.
+func frameSize() (x, y int) {
+	x, y = appStyle.GetFrameSize()
+	return x, y + headerHeight
+}
+
 type state int
 
 const (
@@ -20,44 +37,101 @@ const (
 	showRepos
 	showTasks
 	showTaskOverview
+	showReport
+	showStats
+	showBoard
 
 	showCreateWorkspace
 	showCreateRepo
 	showCreateTask
 
+	showEditWorkspace
+	showEditRepo
+	showEditTask
+
+	showArchived
+	showConfirm
+	showExport
+
+	showInferForm
+	showInferred
+	showEditSession
+
+	showTimesheet
+	showLogTime
+
 	showWaiting
 	showError
 )
 
-type model struct {
+// frame is a previous screen that can be returned to.
+type frame struct {
 	state state
+	// list is set when the next screen replaced the list.
+	list *listModel
+}
+
+type model struct {
+	state   state
+	history []frame
 
-	list    listModel
-	form    formModel
-	overiew overviewModel
+	list      listModel
+	form      formModel
+	overiew   overviewModel
+	report    reportModel
+	stats     statsModel
+	board     boardModel
+	confirm   confirmModel
+	inferred  inferModel
+	timesheet timesheetModel
 
-	workspaces       []models.Workspace
+	workspaces []models.Workspace
+	// archived holds the resources of the archived list.
+	archived         []models.Listable
 	currentWorkspace *models.Workspace
 	currentRepo      *models.Repo
 	currentTask      *models.Task
 
-	dbPath string
-	db     *gorm.DB
+	cfg   config.Config
+	store store.Store
+	// backKey returns to the previous screen from any screen.
+	backKey key.Binding
 
 	height int
 	width  int
 
+	// lastInput is when a key was last pressed, to tell when the user went idle.
+	lastInput time.Time
+	// revision is the revision of the store that was last shown.
+	revision int64
+	// pomodoro follows the pomodoros, which go on when the overview is left.
+	pomodoro pomodoroState
+
 	err         error
 	waitingText string
 }
 
-func New(dbPath string) *tea.Program {
-	m := model{dbPath: dbPath}
-	return tea.NewProgram(m, tea.WithAltScreen())
+func New(cfg config.Config) *tea.Program {
+	return tea.NewProgram(newModel(nil, cfg), tea.WithAltScreen())
+}
+
+// NewWithStore creates a program that uses an already opened store, e.g. an in-memory one.
+func NewWithStore(s store.Store, cfg config.Config) *tea.Program {
+	return tea.NewProgram(newModel(s, cfg), tea.WithAltScreen())
+}
+
+// newModel creates the model of the program, which opens the database of the
+// config when no store is given.
+func newModel(s store.Store, cfg config.Config) model {
+	applyTheme(cfg.Theme)
+	return model{store: s, cfg: cfg, backKey: newBinding(cfg.Keys.Back, "back")}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(initSqliteCmd(m.dbPath))
+	if m.store != nil {
+		return storeCmd(m.store)
+	}
+	return tea.Batch(initSqliteCmd(m.cfg.DatabasePath))
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -69,126 +143,906 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
+		m.lastInput = time.Now()
+		switch {
+		case msg.String() == "ctrl+c":
 			return m, tea.Quit
+		case msg.String() == "q":
+			if !m.typing() {
+				return m, tea.Quit
+			}
+		// a back key that types text is typed rather than navigating.
+		case key.Matches(msg, m.backKey) && !(m.typing() && msg.Type == tea.KeyRunes):
+			if m.canGoBack() {
+				m.back()
+				return m, nil
+			}
 		}
 
-	case dbMsg:
-		m.db = msg.DB
+	case storeMsg:
+		m.store = msg.Store
 		m.waitingText = "fetching workspaces"
-		cmds = append(cmds, listWorkspacesCmd(m.db))
+		cmds = append(cmds, listWorkspacesCmd(m.store), revisionCmd(m.store, 0))
+		if m.cfg.IdleTimeout > 0 {
+			m.lastInput = time.Now()
+			cmds = append(cmds, idleTickCmd())
+		}
+
+	case revisionMsg:
+		cmds = append(cmds, revisionCmd(m.store, watchInterval))
+		switch {
+		case msg.err != nil || msg.Revision == m.revision:
+		case m.revision == 0:
+			// the workspaces are being loaded at the first revision.
+			m.revision = msg.Revision
+		// forms and questions hold on to the resources they are about, so
+		// the reload waits until they are done.
+		case !m.typing() && m.state != showConfirm:
+			m.revision = msg.Revision
+			cmds = append(cmds, m.reloadCmd())
+		}
+
+	case reloadedMsg:
+		cmds = append(cmds, m.reload(msg)...)
+
+	case idleTickMsg:
+		cmds = append(cmds, idleTickCmd())
+		// the user is already being asked something, possibly about their idle time.
+		if m.state != showConfirm {
+			cmds = append(cmds, checkIdleCmd(m.store, m.lastInput, m.cfg.IdleTimeout))
+		}
+
+	case idleMsg:
+		if m.state == showConfirm {
+			break
+		}
+		idle := time.Since(msg.Since).Truncate(time.Minute)
+		m.confirm = newChoice(
+			"Idle",
+			fmt.Sprintf("You have been idle for %s, what should happen to that time?", models.ShortDuration(idle)),
+			secondaryStyle.Render(fmt.Sprintf("%s has been running, but no keys were pressed and no files in %s changed since %s.",
+				msg.Task.Name, msg.Repo.Name, msg.Since.Format("15:04"))),
+			choice{newBinding([]string{"d"}, "discard"), trimIdleCmd(m.store, msg.Task, msg.Repo.Path, msg.Since, false)},
+			choice{newBinding([]string{"s"}, "split into own session"), trimIdleCmd(m.store, msg.Task, msg.Repo.Path, msg.Since, true)},
+			choice{newBinding([]string{"k"}, "keep"), nil},
+		)
+		m.push(showConfirm)
+
+	case trimmedIdleMsg:
+		// the task only needs updating if it is among the tasks being shown.
+		if m.currentRepo == nil || m.currentRepo.ID != msg.Task.RepoID {
+			break
+		}
+		if _, ok := find(m.currentRepo.Tasks, msg.Task.ID); ok {
+			cmds = append(cmds, updateTaskCmd(msg.Task))
+		}
 
 	case chooseResourceMsg:
 		switch m.state {
 		case showWorkspaces:
-			m.currentWorkspace = &m.workspaces[msg.index]
-			m.db.Preload("Repos").Find(m.currentWorkspace)
-			m.list = newList(m.currentWorkspace.Repos, Repo, m.height, m.width)
-			m.state = showRepos
+			i, ok := find(m.workspaces, msg.id)
+			if !ok {
+				break
+			}
+			m.currentWorkspace = &m.workspaces[i]
+			cmds = append(cmds, listReposCmd(m.store, m.currentWorkspace.ID))
 		case showRepos:
-			m.currentRepo = &m.currentWorkspace.Repos[msg.index]
-			m.db.Preload("Tasks").Find(m.currentRepo)
-			m.list = newList(m.currentRepo.Tasks, Task, m.height, m.width)
-			m.state = showTasks
+			i, ok := find(m.currentWorkspace.Repos, msg.id)
+			if !ok {
+				break
+			}
+			m.currentRepo = &m.currentWorkspace.Repos[i]
+			cmds = append(cmds, listTasksCmd(m.store, m.currentRepo.ID, m.currentRepo.Path))
 		case showTasks:
-			m.currentTask = &m.currentRepo.Tasks[msg.index]
-			m.overiew = newOverwiew(*m.currentTask)
-			m.state = showTaskOverview
+			i, ok := find(m.currentRepo.Tasks, msg.id)
+			if !ok {
+				break
+			}
+			m.currentTask = &m.currentRepo.Tasks[i]
+			m.overiew = newOverwiew(*m.currentTask, m.cfg)
+			m.push(showTaskOverview)
+			cmds = append(cmds, m.overiew.init())
+			if len(m.currentTask.StartSHA) > 0 {
+				cmds = append(cmds, taskCommitsCmd(m.store, *m.currentTask, m.currentRepo.Path))
+			}
 		}
 
 	case createResourceMsg:
 		switch m.state {
 		case showWorkspaces:
-			m.state = showCreateWorkspace
-			m.form = newForm(Workspace)
+			m.push(showCreateWorkspace)
+			m.form = newForm(Workspace, m.cfg)
 		case showRepos:
-			m.state = showCreateRepo
-			m.form = newForm(Repo)
+			m.push(showCreateRepo)
+			m.form = newForm(Repo, m.cfg)
 		case showTasks:
-			m.state = showCreateTask
-			m.form = newForm(Task)
+			m.push(showCreateTask)
+			m.form = newForm(Task, m.cfg)
 		}
 		cmds = append(cmds, m.form.init())
 
-	case removeResourceMsg:
+	case editResourceMsg:
+		r, ok := m.resource(msg.id)
+		if !ok {
+			break
+		}
 		switch m.state {
 		case showWorkspaces:
-			res := m.db.Unscoped().Delete(&m.workspaces[msg.index])
-			if res.RowsAffected != 1 {
-				return m, errorCmd(errors.New("remove ineffective"))
-			}
-			m.workspaces = append(m.workspaces[:msg.index], m.workspaces[msg.index+1:]...)
+			m.push(showEditWorkspace)
+			m.form = newEditForm(Workspace, m.cfg, r)
 		case showRepos:
-			res := m.db.Unscoped().Delete(&m.currentWorkspace.Repos[msg.index])
-			if res.RowsAffected != 1 {
-				return m, errorCmd(errors.New("remove ineffective"))
+			m.push(showEditRepo)
+			m.form = newEditForm(Repo, m.cfg, r)
+		case showTasks:
+			m.push(showEditTask)
+			m.form = newEditForm(Task, m.cfg, r)
+		}
+		cmds = append(cmds, m.form.init())
+
+	case saveResourceMsg:
+		cmds = append(cmds, updateResourceCmd(m.store, msg.Resource))
+
+	case updatedResourceMsg:
+		switch r := msg.Resource.(type) {
+		case models.Workspace:
+			if i, ok := find(m.workspaces, r.ID); ok {
+				m.workspaces[i] = r
+			}
+		case models.Repo:
+			if i, ok := find(m.currentWorkspace.Repos, r.ID); ok {
+				m.currentWorkspace.Repos[i] = r
+			}
+		case models.Task:
+			if i, ok := find(m.currentRepo.Tasks, r.ID); ok {
+				m.currentRepo.Tasks[i] = r
 			}
-			m.currentWorkspace.Repos = append(m.currentWorkspace.Repos[:msg.index], m.currentWorkspace.Repos[msg.index+1:]...)
 		}
+		// The changes have been persisted, so we return to the list the resource belongs to.
+		m.back()
 
-	case addWorkspaceMsg:
-		// add workspace to database.
-		res := m.db.Create(&msg.Workspace)
-		if res.RowsAffected != 1 {
-			return m, errorCmd(errors.New("create ineffective"))
+	case removeResourceMsg:
+		r, ok := m.resource(msg.id)
+		if !ok {
+			break
+		}
+		// Removing archives the resource, only archived resources are purged for good.
+		if m.state == showArchived {
+			cmds = append(cmds, countChildrenCmd(m.store, r))
+		} else {
+			cmds = append(cmds, archiveResourceCmd(m.store, r))
 		}
-		m.workspaces = append(m.workspaces, msg.Workspace)
-		cmds = append(cmds, addResourceCmd(msg.Workspace))
-		m.state = showWorkspaces
 
-	case addRepoMsg:
-		err := m.db.Model(m.currentWorkspace).Association("Repos").Append(&msg.Repo)
-		if err != nil {
-			return m, errorCmd(fmt.Errorf("adding repo to workspace: %v", err))
+	case confirmRemoveMsg:
+		details := primaryStyle.Render(fmt.Sprintf("This also removes %s.", msg.Children))
+		if msg.Children.Empty() {
+			details = secondaryStyle.Render("Nothing else belongs to it.")
+		}
+		m.confirm = newConfirm(
+			fmt.Sprintf("Purge %s", msg.Resource.GetName()),
+			fmt.Sprintf("Purge %s? This cannot be undone.", msg.Resource.GetName()),
+			details,
+			"purge",
+			deleteResourceCmd(m.store, msg.Resource),
+			nil,
+		)
+		m.push(showConfirm)
+
+	case answerMsg:
+		m.back()
+		cmds = append(cmds, msg.then)
+
+	case removedResourceMsg:
+		switch m.state {
+		case showWorkspaces:
+			m.workspaces = without(m.workspaces, msg.id)
+		case showRepos:
+			m.currentWorkspace.Repos = without(m.currentWorkspace.Repos, msg.id)
+		case showTasks:
+			m.currentRepo.Tasks = without(m.currentRepo.Tasks, msg.id)
+		case showArchived:
+			m.archived = without(m.archived, msg.id)
+		}
+
+	case showArchivedMsg:
+		switch m.state {
+		case showWorkspaces:
+			cmds = append(cmds, listArchivedCmd(m.store, Workspace, 0))
+		case showRepos:
+			cmds = append(cmds, listArchivedCmd(m.store, Repo, m.currentWorkspace.ID))
+		case showTasks:
+			cmds = append(cmds, listArchivedCmd(m.store, Task, m.currentRepo.ID))
+		}
+
+	case listArchivedMsg:
+		m.archived = msg.Resources
+		m.pushList(showArchived, newArchivedList(m.archived, msg.Type, m.cfg.Keys, m.height, m.width))
+
+	case restoreResourceMsg:
+		if i, ok := find(m.archived, msg.id); ok {
+			cmds = append(cmds, unarchiveResourceCmd(m.store, m.archived[i]))
 		}
-		m.db.Preload("Repos").Find(m.currentWorkspace)
-		cmds = append(cmds, addResourceCmd(msg.Repo))
-		m.state = showRepos
+
+	case restoredResourceMsg:
+		m.archived = without(m.archived, msg.Resource.GetID())
+		switch r := msg.Resource.(type) {
+		case models.Workspace:
+			m.workspaces = append(m.workspaces, r)
+		case models.Repo:
+			m.currentWorkspace.Repos = append(m.currentWorkspace.Repos, r)
+		case models.Task:
+			m.currentRepo.Tasks = append(m.currentRepo.Tasks, r)
+		}
+		// The list the resource was archived from is waiting in the history.
+		if len(m.history) == 0 {
+			break
+		}
+		if f := m.history[len(m.history)-1]; f.list != nil {
+			cmds = append(cmds, f.list.insert(msg.Resource))
+		}
+
+	case addWorkspaceMsg:
+		cmds = append(cmds, createWorkspaceCmd(m.store, msg.Workspace))
+
+	case addRepoMsg:
+		msg.Repo.WorkspaceID = m.currentWorkspace.ID
+		cmds = append(cmds, createRepoCmd(m.store, msg.Repo))
 
 	case addTaskMsg:
-		err := m.db.Model(m.currentRepo).Association("Tasks").Append(&msg.Task)
-		if err != nil {
-			return m, errorCmd(fmt.Errorf("adding task to repo: %v", err))
+		msg.Task.RepoID = m.currentRepo.ID
+		cmds = append(cmds, createTaskCmd(m.store, msg.Task))
+
+	case addResourceMsg:
+		switch r := msg.Resource.(type) {
+		case models.Workspace:
+			m.workspaces = append(m.workspaces, r)
+		case models.Repo:
+			m.currentWorkspace.Repos = append(m.currentWorkspace.Repos, r)
+		case models.Task:
+			m.currentRepo.Tasks = append(m.currentRepo.Tasks, r)
+		}
+		// The resource has been persisted, so we return to the list it belongs to.
+		m.back()
+
+	case startTaskMsg:
+		// starting a timer ends the break between pomodoros.
+		m.pomodoro.breakEnds = time.Time{}
+		task, ok := m.task(msg.id)
+		if !ok || task.CompletedAt.Valid || task.Running() {
+			break
+		}
+		if m.cfg.BranchTemplate != "" && !msg.branchChecked {
+			cmds = append(cmds, offerBranchCmd(msg.id, task, m.cfg.BranchTemplate, m.currentRepo.Path))
+			break
+		}
+		cmds = append(cmds, startTimerCmd(m.store, task, m.currentRepo.Path))
+
+	case offerBranchMsg:
+		task, ok := m.task(msg.id)
+		if !ok {
+			break
+		}
+		action, question := "check out", fmt.Sprintf("Check out %s before starting %s?", msg.Branch, task.Name)
+		if !msg.Exists {
+			action, question = "create", fmt.Sprintf("Create %s before starting %s?", msg.Branch, task.Name)
+		}
+		current := msg.Current
+		if current == "" {
+			current = "a detached HEAD"
+		}
+		m.confirm = newConfirm(
+			"Branch",
+			question,
+			secondaryStyle.Render(fmt.Sprintf("%s is currently on %s, answering no starts the task there.", m.currentRepo.Name, current)),
+			action,
+			checkoutAndStartCmd(m.store, task, m.currentRepo.Path, msg.Branch),
+			startOnBranchCmd(msg.id),
+		)
+		m.push(showConfirm)
+
+	case startPomodoroMsg:
+		task, ok := m.task(msg.id)
+		if !ok || task.CompletedAt.Valid || task.Running() {
+			break
+		}
+		// a break can be cut short by starting the next pomodoro, while the
+		// pomodoros of another task start over.
+		if m.pomodoro.task.ID != task.ID {
+			m.pomodoro = pomodoroState{ticking: m.pomodoro.ticking}
+		}
+		m.pomodoro.task, m.pomodoro.repoPath = task, m.currentRepo.Path
+		m.pomodoro.breakEnds = time.Time{}
+		cmds = append(cmds, startPomodoroTimerCmd(m.store, task.ID, m.currentRepo.Path))
+
+	case pomodoroTickMsg:
+		cmds = append(cmds, m.pomodoroTick(msg.Time)...)
+
+	case finishedPomodoroMsg:
+		m.pomodoro.finishing = false
+		switch {
+		case errors.Is(msg.err, tracker.ErrNoPomodoro), errors.Is(msg.err, store.ErrNotFound):
+			// the pomodoro was paused or removed elsewhere, e.g. from the command line.
+			m.pomodoro.task = msg.Task
+			if msg.Task.ID != 0 {
+				cmds = append(cmds, updateTaskCmd(msg.Task))
+			}
+		case msg.err != nil:
+			// the ticker tries again, without piling up errors.
+			if m.state != showError {
+				m.err = msg.err
+				m.push(showError)
+			}
+		default:
+			// a finished pomodoro is followed by a break, after which the next one starts.
+			m.pomodoro.finished++
+			last := msg.Task.TimeEntries[len(msg.Task.TimeEntries)-1]
+			m.pomodoro.breakEnds = last.EndedAt.Time.Add(m.cfg.Pomodoro.Break(m.pomodoro.finished))
+			cmds = append(cmds, updateTaskCmd(msg.Task))
 		}
-		m.db.Preload("Tasks").Find(m.currentRepo)
-		cmds = append(cmds, addResourceCmd(msg.Task))
-		m.state = showTasks
+
+	case pauseTaskMsg:
+		// pausing during a break ends the pomodoros.
+		if m.pomodoro.task.ID == msg.id && !m.pomodoro.breakEnds.IsZero() {
+			m.pomodoro.breakEnds = time.Time{}
+			break
+		}
+		task, ok := m.task(msg.id)
+		if !ok || !task.Running() {
+			break
+		}
+		cmds = append(cmds, pauseTimerCmd(m.store, task, m.currentRepo.Path))
+
+	case annotateTaskMsg:
+		task, ok := m.task(msg.id)
+		if !ok || len(task.TimeEntries) == 0 {
+			break
+		}
+		cmds = append(cmds, annotateEntryCmd(m.store, task.TimeEntries[len(task.TimeEntries)-1], msg.note))
+
+	case completeTaskMsg:
+		task, ok := m.task(msg.id)
+		if !ok || task.CompletedAt.Valid || !task.StartedAt.Valid {
+			break
+		}
+		cmds = append(cmds, completeTimerCmd(m.store, task, m.currentRepo.Path))
+
+	case startedTaskMsg:
+		cmds = append(cmds, updateTaskCmd(msg.Task))
+		for _, t := range msg.Paused {
+			cmds = append(cmds, updateTaskCmd(t))
+		}
+
+	case updateTaskMsg:
+		if msg.Task.ID == m.pomodoro.task.ID {
+			m.pomodoro.task = msg.Task
+			cmds = append(cmds, m.tickPomodoro())
+		}
+		if m.currentRepo == nil {
+			break
+		}
+		i, ok := find(m.currentRepo.Tasks, msg.Task.ID)
+		if !ok {
+			break
+		}
+		m.currentRepo.Tasks[i] = msg.Task
+		// the list is not in focus while showing the overview, so we pass the update on manually.
+		if m.state == showTaskOverview {
+			newList, cmd := m.list.update(msg)
+			m.list = newList
+			cmds = append(cmds, cmd)
+			if len(msg.Task.StartSHA) > 0 {
+				cmds = append(cmds, taskCommitsCmd(m.store, msg.Task, m.currentRepo.Path))
+			}
+		}
+
+	case reportResourceMsg:
+		if i, ok := find(m.workspaces, msg.id); ok {
+			cmds = append(cmds, reportCmd(m.store, m.workspaces[i], msg.archived))
+		}
+
+	case reportMsg:
+		// Toggling archived data rebuilds the report that is already shown.
+		if m.state != showReport {
+			m.push(showReport)
+		}
+		m.report = newReport(msg.id, msg.archived, msg.Title, msg.Report, m.cfg.Keys, m.height, m.width)
+
+	case statsResourceMsg:
+		r, ok := m.resource(msg.id)
+		switch {
+		case !ok:
+		case m.state == showWorkspaces:
+			cmds = append(cmds, statsCmd(m.store, r.(models.Workspace), ""))
+		case m.state == showRepos:
+			cmds = append(cmds, statsCmd(m.store, *m.currentWorkspace, r.GetName()))
+		}
+
+	case statsMsg:
+		m.stats = newStats(msg, m.cfg, m.height, m.width)
+		m.push(showStats)
+
+	case boardResourceMsg:
+		r, ok := m.resource(msg.id)
+		switch {
+		case !ok:
+		case m.state == showWorkspaces:
+			cmds = append(cmds, boardCmd(m.store, r.(models.Workspace), ""))
+		case m.state == showRepos:
+			cmds = append(cmds, boardCmd(m.store, *m.currentWorkspace, r.GetName()))
+		}
+
+	case boardMsg:
+		// reloading replaces the board that is already shown.
+		if m.state == showBoard {
+			m.board.setBoard(msg)
+			break
+		}
+		m.board = newBoard(msg, m.cfg, m.height, m.width)
+		m.push(showBoard)
+
+	case moveTaskMsg:
+		cmds = append(cmds, moveToColumnCmd(m.store, msg.Task, msg.Repo.Path, m.cfg.Columns, msg.To))
+
+	case movedTaskMsg:
+		m.board.setTask(msg.Task)
+
+	case exportResourceMsg:
+		r, ok := m.resource(msg.id)
+		if !ok {
+			break
+		}
+		var filter export.Filter
+		switch m.state {
+		case showWorkspaces:
+			filter.Workspace = r.GetName()
+		case showRepos:
+			filter.Workspace = m.currentWorkspace.Name
+			filter.Repo = r.GetName()
+		}
+		m.form = newExportForm(filter)
+		m.push(showExport)
+		cmds = append(cmds, m.form.init())
+
+	case saveExportMsg:
+		cmds = append(cmds, exportCmd(m.store, msg.Path, msg.Format, msg.Filter))
+
+	case exportedMsg:
+		m.back()
+		cmds = append(cmds, m.list.list.NewStatusMessage(fmt.Sprintf("Exported to %s", msg.Path)))
+
+	case inferResourceMsg:
+		i, ok := find(m.currentWorkspace.Repos, msg.id)
+		if !ok {
+			break
+		}
+		m.form = newInferForm(m.currentWorkspace.Repos[i])
+		m.push(showInferForm)
+		cmds = append(cmds, m.form.init())
+
+	case inferRangeMsg:
+		if i, ok := find(m.currentWorkspace.Repos, msg.id); ok {
+			cmds = append(cmds, inferCmd(m.store, m.currentWorkspace.Repos[i], msg.From, msg.To, m.cfg.InferGap, m.cfg.InferLeadIn))
+		}
+
+	case inferredMsg:
+		// the sessions replace the form they were inferred from.
+		m.back()
+		m.inferred = newInfer(msg, m.cfg.Keys, m.height)
+		m.push(showInferred)
+
+	case acceptProposalMsg:
+		cmds = append(cmds, acceptCmd(m.store, msg.index, m.inferred.proposals[msg.index], m.inferred.repo.Path))
+
+	case editProposalMsg:
+		m.form = newSessionForm(msg.index, m.inferred.proposals[msg.index], m.inferred.tasks)
+		m.push(showEditSession)
+		cmds = append(cmds, m.form.init())
+
+	case saveProposalMsg:
+		m.back()
+		m.inferred.edit(msg)
+
+	case showTimesheetMsg:
+		cmds = append(cmds, timesheetCmd(m.store, timesheet.StartOfWeek(time.Now()), true))
+
+	case loadTimesheetMsg:
+		cmds = append(cmds, timesheetCmd(m.store, msg.From, msg.Week))
+
+	case timesheetMsg:
+		// moving between days and weeks replaces the timesheet that is already shown.
+		if m.state == showTimesheet {
+			m.timesheet.setSheet(msg)
+			break
+		}
+		m.timesheet = newTimesheet(msg, m.cfg.Keys, m.height)
+		m.push(showTimesheet)
+
+	case logTimeMsg:
+		m.form = newEntryForm(msg.Task, msg.Day)
+		m.push(showLogTime)
+		cmds = append(cmds, m.form.init())
+
+	case saveEntryMsg:
+		if task, _, ok := m.timesheet.selected(); ok {
+			cmds = append(cmds, logEntryCmd(m.store, task, msg.Start, msg.End, msg.Note))
+		}
+
+	case loggedTimeMsg:
+		m.back()
+		cmds = append(cmds, timesheetCmd(m.store, m.timesheet.sheet.From, m.timesheet.week))
+
+	case backMsg:
+		m.back()
 
 	case listWorkspacesMsg:
 		m.workspaces = msg.Workspaces
-		m.list = newList(m.workspaces, Workspace, m.height, m.width)
+		m.list = newList(m.workspaces, Workspace, m.cfg.Keys, m.height, m.width)
 		m.state = showWorkspaces
 
+	case listReposMsg:
+		m.currentWorkspace.Repos = msg.Repos
+		m.pushList(showRepos, newList(m.currentWorkspace.Repos, Repo, m.cfg.Keys, m.height, m.width))
+
+	case listTasksMsg:
+		m.currentRepo.Tasks = msg.Tasks
+		l := newList(m.currentRepo.Tasks, Task, m.cfg.Keys, m.height, m.width)
+		l.setBranchStatuses(msg.BranchStatuses)
+		m.pushList(showTasks, l)
+
 	case errorMsg:
 		m.err = msg
-		m.state = showError
+		m.push(showError)
 	}
 
 	switch m.state {
-	case showWorkspaces, showRepos, showTasks:
+	case showWorkspaces, showRepos, showTasks, showArchived:
 		newList, cmd := m.list.update(msg)
 		m.list = newList
 		cmds = append(cmds, cmd)
-	case showCreateWorkspace, showCreateRepo, showCreateTask:
+	case showCreateWorkspace, showCreateRepo, showCreateTask, showEditWorkspace, showEditRepo, showEditTask, showExport, showInferForm, showEditSession, showLogTime:
 		newForm, cmd := m.form.update(msg)
 		m.form = newForm
 		cmds = append(cmds, cmd)
+	case showTaskOverview:
+		newOverview, cmd := m.overiew.update(msg)
+		m.overiew = newOverview
+		cmds = append(cmds, cmd)
+	case showReport:
+		newReport, cmd := m.report.update(msg)
+		m.report = newReport
+		cmds = append(cmds, cmd)
+	case showStats:
+		newStats, cmd := m.stats.update(msg)
+		m.stats = newStats
+		cmds = append(cmds, cmd)
+	case showBoard:
+		newBoard, cmd := m.board.update(msg)
+		m.board = newBoard
+		cmds = append(cmds, cmd)
+	case showConfirm:
+		newConfirm, cmd := m.confirm.update(msg)
+		m.confirm = newConfirm
+		cmds = append(cmds, cmd)
+	case showInferred:
+		newInferred, cmd := m.inferred.update(msg)
+		m.inferred = newInferred
+		cmds = append(cmds, cmd)
+	case showTimesheet:
+		newTimesheet, cmd := m.timesheet.update(msg)
+		m.timesheet = newTimesheet
+		cmds = append(cmds, cmd)
 	}
 	return m, tea.Batch(cmds...)
 }
 
+// reloadCmd reloads the resources being browsed, after another process changed them.
+func (m model) reloadCmd() tea.Cmd {
+	var workspaceID, repoID uint
+	var repoPath string
+	if m.currentWorkspace != nil {
+		workspaceID = m.currentWorkspace.ID
+	}
+	if m.currentRepo != nil {
+		repoID, repoPath = m.currentRepo.ID, m.currentRepo.Path
+	}
+	cmds := []tea.Cmd{reloadCmd(m.store, workspaceID, repoID, repoPath)}
+	switch m.state {
+	case showTimesheet:
+		cmds = append(cmds, timesheetCmd(m.store, m.timesheet.sheet.From, m.timesheet.week))
+	case showBoard:
+		cmds = append(cmds, boardCmd(m.store, m.board.workspace, m.board.repo))
+	}
+	return tea.Batch(cmds...)
+}
+
+// reload replaces the resources being browsed, and the lists and the overview
+// that show them. The current workspace, repo and task are found by their IDs,
+// as they may have moved, and the screens of those that are gone are left.
+func (m *model) reload(msg reloadedMsg) []tea.Cmd {
+	m.workspaces = msg.Workspaces
+	repos := m.currentWorkspace != nil && m.currentWorkspace.ID == msg.WorkspaceID
+	tasks := repos && m.currentRepo != nil && m.currentRepo.ID == msg.RepoID
+	if m.currentWorkspace != nil {
+		if i, ok := find(m.workspaces, m.currentWorkspace.ID); ok {
+			m.currentWorkspace = &m.workspaces[i]
+		} else {
+			m.leave(showRepos)
+			m.currentWorkspace, m.currentRepo, m.currentTask = nil, nil, nil
+			repos, tasks = false, false
+		}
+	}
+	if repos {
+		m.currentWorkspace.Repos = msg.Repos
+		if m.currentRepo != nil {
+			if i, ok := find(msg.Repos, m.currentRepo.ID); ok {
+				m.currentRepo = &msg.Repos[i]
+			} else {
+				m.leave(showTasks)
+				m.currentRepo, m.currentTask = nil, nil
+				tasks = false
+			}
+		}
+	}
+
+	var cmds []tea.Cmd
+	if i, ok := find(msg.Tasks, m.pomodoro.task.ID); ok {
+		m.pomodoro.task = msg.Tasks[i]
+	}
+	if tasks {
+		m.currentRepo.Tasks = msg.Tasks
+		if m.currentTask != nil {
+			if i, ok := find(msg.Tasks, m.currentTask.ID); ok {
+				m.currentTask = &msg.Tasks[i]
+				m.overiew.setTask(*m.currentTask)
+				if len(m.currentTask.StartSHA) > 0 {
+					cmds = append(cmds, taskCommitsCmd(m.store, *m.currentTask, m.currentRepo.Path))
+				}
+			} else {
+				m.leave(showTaskOverview)
+				m.currentTask = nil
+			}
+		}
+	}
+
+	setList := func(l *listModel, s state) {
+		switch {
+		case s == showWorkspaces:
+			cmds = append(cmds, setItems(l, msg.Workspaces))
+		case s == showRepos && repos:
+			cmds = append(cmds, setItems(l, msg.Repos))
+		case s == showTasks && tasks:
+			cmds = append(cmds, setItems(l, msg.Tasks))
+			l.setBranchStatuses(msg.BranchStatuses)
+		}
+	}
+	// the current list belongs to the last screen with a list, the lists of
+	// the screens before it wait in the history.
+	current := m.state
+	for i := len(m.history) - 1; i >= 0 && !isList(current); i-- {
+		current = m.history[i].state
+	}
+	setList(&m.list, current)
+	for _, f := range m.history {
+		if f.list != nil {
+			setList(f.list, f.state)
+		}
+	}
+	return cmds
+}
+
+// isList reports whether the screen shows a list.
+func isList(s state) bool {
+	switch s {
+	case showWorkspaces, showRepos, showTasks, showArchived:
+		return true
+	}
+	return false
+}
+
+// resource returns the resource with id in the list being shown.
+func (m model) resource(id uint) (models.Listable, bool) {
+	switch m.state {
+	case showWorkspaces:
+		return lookup(m.workspaces, id)
+	case showRepos:
+		return lookup(m.currentWorkspace.Repos, id)
+	case showTasks:
+		return lookup(m.currentRepo.Tasks, id)
+	case showArchived:
+		return lookup(m.archived, id)
+	}
+	return nil, false
+}
+
+// task returns the task with id in the current repo.
+func (m model) task(id uint) (models.Task, bool) {
+	if m.currentRepo == nil {
+		return models.Task{}, false
+	}
+	i, ok := find(m.currentRepo.Tasks, id)
+	if !ok {
+		return models.Task{}, false
+	}
+	return m.currentRepo.Tasks[i], true
+}
+
+// find returns the position of the resource with id among resources.
+func find[L models.Listable](resources []L, id uint) (int, bool) {
+	for i, r := range resources {
+		if r.GetID() == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// lookup returns the resource with id among resources.
+func lookup[L models.Listable](resources []L, id uint) (models.Listable, bool) {
+	if i, ok := find(resources, id); ok {
+		return resources[i], true
+	}
+	return nil, false
+}
+
+// without returns the resources other than the one with id.
+func without[L models.Listable](resources []L, id uint) []L {
+	if i, ok := find(resources, id); ok {
+		return append(resources[:i], resources[i+1:]...)
+	}
+	return resources
+}
+
+// leave returns to the screen before s, if s is shown or waits in the history.
+func (m *model) leave(s state) {
+	for len(m.history) > 0 && m.showing(s) {
+		m.back()
+	}
+}
+
+// showing reports whether s is shown or waits in the history.
+func (m model) showing(s state) bool {
+	if m.state == s {
+		return true
+	}
+	for _, f := range m.history {
+		if f.state == s {
+			return true
+		}
+	}
+	return false
+}
+
+// push moves to the next screen, remembering the current one.
+func (m *model) push(next state) {
+	m.history = append(m.history, frame{state: m.state})
+	m.state = next
+}
+
+// pushList moves to a screen that shows another list.
+func (m *model) pushList(next state, l listModel) {
+	prev := m.list
+	m.history = append(m.history, frame{state: m.state, list: &prev})
+	m.list = l
+	m.state = next
+}
+
+// back returns to the previous screen.
+func (m *model) back() {
+	if len(m.history) == 0 {
+		return
+	}
+	f := m.history[len(m.history)-1]
+	m.history = m.history[:len(m.history)-1]
+	m.state = f.state
+	if f.list != nil {
+		m.list = *f.list
+	}
+	// the size of the window may have changed while the list was hidden.
+	m.list.setSize(m.height, m.width)
+}
+
+// canGoBack reports whether the back key should return to the previous screen,
+// rather than being handled by the current one.
+func (m model) canGoBack() bool {
+	if len(m.history) == 0 {
+		return false
+	}
+	switch m.state {
+	case showWorkspaces, showRepos, showTasks, showArchived:
+		// esc clears the filter of a list before it navigates.
+		return m.list.list.FilterState() == list.Unfiltered
+	case showTaskOverview:
+		return !m.overiew.editing
+	}
+	return true
+}
+
+// breadcrumbs describes the path to the current screen, e.g. "Workspaces › work › chronograph".
+func (m model) breadcrumbs() string {
+	crumbs := []string{"Workspaces"}
+	states := make([]state, 0, len(m.history)+1)
+	for _, f := range m.history {
+		states = append(states, f.state)
+	}
+	for _, s := range append(states, m.state) {
+		switch s {
+		case showRepos:
+			crumbs = append(crumbs, m.currentWorkspace.Name)
+		case showTasks:
+			crumbs = append(crumbs, m.currentRepo.Name)
+		case showTaskOverview:
+			crumbs = append(crumbs, m.currentTask.Name)
+		case showReport:
+			crumbs = append(crumbs, "Report")
+		case showStats:
+			crumbs = append(crumbs, "Statistics")
+		case showBoard:
+			crumbs = append(crumbs, "Board")
+		case showCreateWorkspace, showCreateRepo, showCreateTask:
+			crumbs = append(crumbs, fmt.Sprintf("New %s", m.form.resource))
+		case showEditWorkspace, showEditRepo, showEditTask:
+			crumbs = append(crumbs, fmt.Sprintf("Edit %s", m.form.original.GetName()))
+		case showArchived:
+			crumbs = append(crumbs, "Archived")
+		case showExport:
+			crumbs = append(crumbs, "Export")
+		case showInferForm:
+			crumbs = append(crumbs, "Infer sessions")
+		case showInferred:
+			crumbs = append(crumbs, "Sessions")
+		case showEditSession:
+			crumbs = append(crumbs, "Edit session")
+		case showTimesheet:
+			crumbs = append(crumbs, "Timesheet")
+		case showLogTime:
+			crumbs = append(crumbs, "Log time")
+		case showConfirm:
+			crumbs = append(crumbs, m.confirm.crumb)
+		case showError:
+			crumbs = append(crumbs, "Error")
+		}
+	}
+	return strings.Join(crumbs, " › ")
+}
+
+// typing reports whether the user is currently entering text, in which case
+// key strokes should not be interpreted as commands.
+func (m model) typing() bool {
+	switch m.state {
+	case showCreateWorkspace, showCreateRepo, showCreateTask, showEditWorkspace, showEditRepo, showEditTask, showExport, showInferForm, showEditSession, showLogTime:
+		return true
+	case showWorkspaces, showRepos, showTasks, showArchived:
+		return m.list.list.FilterState() == list.Filtering
+	case showTaskOverview:
+		return m.overiew.editing
+	}
+	return false
+}
+
 func (m model) View() string {
+	var content string
 	switch m.state {
 	case showError:
-		return m.errorView()
-	case showWorkspaces, showRepos, showTasks:
-		return appStyle.Render(m.list.view())
-	case showCreateWorkspace, showCreateRepo, showCreateTask:
-		return appStyle.Render(m.form.view())
+		content = m.errorView()
+	case showWorkspaces, showRepos, showTasks, showArchived:
+		content = m.list.view()
+	case showCreateWorkspace, showCreateRepo, showCreateTask, showEditWorkspace, showEditRepo, showEditTask, showExport, showInferForm, showEditSession, showLogTime:
+		content = m.form.view()
 	case showTaskOverview:
-		return appStyle.Render(m.overiew.view())
+		content = m.overiew.view(m.pomodoro)
+	case showReport:
+		content = m.report.view()
+	case showStats:
+		content = m.stats.view()
+	case showBoard:
+		content = m.board.view()
+	case showConfirm:
+		content = m.confirm.view()
+	case showInferred:
+		content = m.inferred.view()
+	case showTimesheet:
+		content = m.timesheet.view()
 	default:
 		return ""
 	}
+	return appStyle.Render(fmt.Sprintf("%s\n\n%s", secondaryDimmedStyle.Render(m.breadcrumbs()), content))
 }
@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/tracker"
+)
+
+// wait is how long a command may take to return its message, the commands
+// that take longer are tickers, which are dropped.
+const wait = 50 * time.Millisecond
+
+// program runs the model of the TUI without a terminal, feeding the messages
+// of the commands back to it until none are left.
+type program struct {
+	t *testing.T
+	m model
+}
+
+// testConfig is the default config without the features that run on their
+// own, like idle detection.
+func testConfig() config.Config {
+	cfg := config.Default()
+	cfg.Theme = config.ThemePlain
+	cfg.IdleTimeout = 0
+	cfg.BranchTemplate = ""
+	return cfg
+}
+
+// newProgram starts the TUI on the store like NewWithStore.
+func newProgram(t *testing.T, s store.Store, cfg config.Config) *program {
+	t.Helper()
+	p := &program{t: t, m: newModel(s, cfg)}
+	p.send(tea.WindowSizeMsg{Width: 120, Height: 40})
+	p.run(p.m.Init())
+	p.wantState(showWorkspaces)
+	return p
+}
+
+// send updates the model with msg and runs the commands that follow from it.
+func (p *program) send(msg tea.Msg) {
+	p.t.Helper()
+	for queue := []tea.Msg{msg}; len(queue) > 0; queue = queue[1:] {
+		next, cmd := p.m.Update(queue[0])
+		p.m = next.(model)
+		if p.m.state == showError {
+			p.t.Fatalf("the TUI shows an error: %v", p.m.err)
+		}
+		queue = append(queue, results(cmd)...)
+	}
+}
+
+// run runs cmd and sends the messages that follow from it.
+func (p *program) run(cmd tea.Cmd) {
+	p.t.Helper()
+	for _, msg := range results(cmd) {
+		p.send(msg)
+	}
+}
+
+// press sends the key presses of keys, e.g. "enter", "esc" or "e".
+func (p *program) press(keys ...string) {
+	p.t.Helper()
+	for _, k := range keys {
+		switch k {
+		case "enter":
+			p.send(tea.KeyMsg{Type: tea.KeyEnter})
+		case "esc":
+			p.send(tea.KeyMsg{Type: tea.KeyEsc})
+		default:
+			p.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		}
+	}
+}
+
+func (p *program) wantState(want state) {
+	p.t.Helper()
+	if p.m.state != want {
+		p.t.Fatalf("the TUI shows %s, want %s", p.m.breadcrumbs(), stateNames[want])
+	}
+}
+
+var stateNames = map[state]string{
+	showWorkspaces:   "the workspaces",
+	showRepos:        "the repos",
+	showTasks:        "the tasks",
+	showTaskOverview: "the overview",
+	showEditTask:     "the task form",
+}
+
+// results runs cmd and the commands it batches, and returns their messages.
+func results(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(wait):
+		return nil
+	}
+	// tea.Batch returns a message of the commands it batches.
+	if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice && v.Type().Elem() == reflect.TypeOf(cmd) {
+		var msgs []tea.Msg
+		for i := 0; i < v.Len(); i++ {
+			msgs = append(msgs, results(v.Index(i).Interface().(tea.Cmd))...)
+		}
+		return msgs
+	}
+	if msg == nil {
+		return nil
+	}
+	return []tea.Msg{msg}
+}
+
+// seed creates a workspace with a repo that has the tasks.
+func seed(t *testing.T, s store.Store, tasks ...string) []models.Task {
+	t.Helper()
+	workspace := models.Workspace{Name: "work"}
+	if err := s.CreateWorkspace(&workspace); err != nil {
+		t.Fatal(err)
+	}
+	repo := models.Repo{Name: "chronograph", WorkspaceID: workspace.ID}
+	if err := s.CreateRepo(&repo); err != nil {
+		t.Fatal(err)
+	}
+	created := make([]models.Task, len(tasks))
+	for i, name := range tasks {
+		created[i] = models.Task{Name: name, RepoID: repo.ID}
+		if err := s.CreateTask(&created[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return created
+}
+
+func TestNavigation(t *testing.T) {
+	s := store.NewMemory()
+	seed(t, s, "tests")
+	p := newProgram(t, s, testConfig())
+
+	p.press("enter")
+	p.wantState(showRepos)
+	p.press("enter")
+	p.wantState(showTasks)
+	p.press("enter")
+	p.wantState(showTaskOverview)
+	if got, want := p.m.breadcrumbs(), "Workspaces › work › chronograph › tests"; got != want {
+		t.Errorf("breadcrumbs are %q, want %q", got, want)
+	}
+	p.press("esc", "esc")
+	p.wantState(showRepos)
+}
+
+func TestBackKey(t *testing.T) {
+	s := store.NewMemory()
+	seed(t, s, "tests")
+	cfg := testConfig()
+	cfg.Keys.Back = []string{"b", "ctrl+b"}
+	p := newProgram(t, s, cfg)
+
+	p.press("enter", "enter", "esc")
+	p.wantState(showTasks)
+	p.press("b")
+	p.wantState(showRepos)
+
+	// the back key is typed into forms rather than leaving them.
+	p.press("enter", "e", "b")
+	p.wantState(showEditTask)
+	if got := p.m.form.inputs[0].Input.Value(); got != "testsb" {
+		t.Errorf("the name is %q after typing the back key, want %q", got, "testsb")
+	}
+	p.send(tea.KeyMsg{Type: tea.KeyCtrlB})
+	p.wantState(showTasks)
+}
+
+func TestStart(t *testing.T) {
+	s := store.NewMemory()
+	tasks := seed(t, s, "tests", "docs")
+	if _, err := tracker.Start(s, tasks[1], ""); err != nil {
+		t.Fatal(err)
+	}
+	p := newProgram(t, s, testConfig())
+
+	p.press("enter", "enter", "enter", "s")
+	running, err := s.RunningTasks()
+	if err != nil || len(running) != 1 || running[0].ID != tasks[0].ID {
+		t.Fatalf("%d tasks are running after starting %s, %v", len(running), tasks[0].Name, err)
+	}
+	// both tasks are shown as they are in the store.
+	for _, task := range p.m.currentRepo.Tasks {
+		if want := task.ID == tasks[0].ID; task.Running() != want {
+			t.Errorf("%s is shown running %t, want %t", task.Name, task.Running(), want)
+		}
+	}
+}
+
+func TestEditDuringTracking(t *testing.T) {
+	s := store.NewMemory()
+	tasks := seed(t, s, "tests")
+	p := newProgram(t, s, testConfig())
+	p.press("enter", "enter", "e")
+	p.wantState(showEditTask)
+
+	// another instance starts the task while the form is open.
+	if _, err := tracker.Start(s, tasks[0], ""); err != nil {
+		t.Fatal(err)
+	}
+	// the name is changed and the form is submitted from its last input.
+	p.press("s", "enter", "enter", "enter", "enter", "enter")
+	p.wantState(showTasks)
+
+	task, err := s.Task(tasks[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Name != "testss" {
+		t.Errorf("the task is named %q, want the edited name", task.Name)
+	}
+	if !task.StartedAt.Valid || !task.Running() {
+		t.Errorf("saving the form undid starting the task: %+v", task)
+	}
+}
+
+func TestPomodoroOutsideOverview(t *testing.T) {
+	s := store.NewMemory()
+	tasks := seed(t, s, "tests")
+	cfg := testConfig()
+	p := newProgram(t, s, cfg)
+
+	p.press("enter", "enter", "enter", "o", "esc", "esc")
+	p.wantState(showRepos)
+	started := p.m.pomodoro.task.ActiveEntry()
+	if started == nil || !started.Pomodoro {
+		t.Fatalf("no pomodoro is running: %+v", p.m.pomodoro.task)
+	}
+
+	// the pomodoro is finished when it is due, though the overview is left.
+	due := started.StartedAt.Add(cfg.Pomodoro.Work)
+	p.send(pomodoroTickMsg{Time: due})
+	task, err := s.Task(tasks[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Running() || task.Pomodoros() != 1 {
+		t.Fatalf("the pomodoro is not finished when it is due: %+v", task)
+	}
+	breakEnds := due.Add(cfg.Pomodoro.Break(1))
+	if !p.m.pomodoro.breakEnds.Equal(breakEnds) {
+		t.Errorf("the break ends at %s, want %s", p.m.pomodoro.breakEnds, breakEnds)
+	}
+
+	// the next pomodoro starts after the break.
+	p.send(pomodoroTickMsg{Time: breakEnds})
+	task, err = s.Task(tasks[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := task.ActiveEntry(); e == nil || !e.Pomodoro {
+		t.Errorf("the next pomodoro does not start after the break: %+v", task)
+	}
+}
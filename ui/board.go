@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/models"
+)
+
+// cardHeight is the number of lines a task takes on the board, including the
+// blank line below it.
+const cardHeight = 3
+
+// boardModel shows the tasks of a workspace or a repo as cards in the columns
+// of their states, where they can be moved from column to column.
+type boardModel struct {
+	title     string
+	workspace models.Workspace
+	// repo names the repo the board is of, it is empty for a whole workspace.
+	repo    string
+	columns []string
+	cards   [][]card
+	// col and row is the card under the cursor.
+	col, row      int
+	height, width int
+
+	keys boardKeyMap
+	help help.Model
+}
+
+// card is a task on the board, along with the repo it belongs to.
+type card struct {
+	task models.Task
+	repo models.Repo
+}
+
+type boardKeyMap struct {
+	up        key.Binding
+	down      key.Binding
+	left      key.Binding
+	right     key.Binding
+	moveLeft  key.Binding
+	moveRight key.Binding
+	back      key.Binding
+}
+
+func (k boardKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.left, k.right, k.moveLeft, k.moveRight, k.back}
+}
+
+func (k boardKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+func newBoard(msg boardMsg, cfg config.Config, height, width int) boardModel {
+	m := boardModel{
+		columns: cfg.Columns,
+		height:  height,
+		width:   width,
+		keys: boardKeyMap{
+			up:        newBinding(cfg.Keys.Up, "up"),
+			down:      newBinding(cfg.Keys.Down, "down"),
+			left:      newBinding(cfg.Keys.Left, "previous column"),
+			right:     newBinding(cfg.Keys.Right, "next column"),
+			moveLeft:  newBinding(cfg.Keys.MoveLeft, "move left"),
+			moveRight: newBinding(cfg.Keys.MoveRight, "move right"),
+			back:      newBinding(cfg.Keys.Back, "back"),
+		},
+		help: help.New(),
+	}
+	m.setBoard(msg)
+	return m
+}
+
+// setBoard sorts the tasks of the workspace into the columns, keeping the
+// cursor on the task it was on if the task is still there.
+func (m *boardModel) setBoard(msg boardMsg) {
+	selected, _ := m.selected()
+	m.title = msg.Title
+	m.workspace = msg.Workspace
+	m.repo = msg.Repo
+	m.cards = make([][]card, len(m.columns))
+	index := make(map[string]int, len(m.columns))
+	for i, c := range m.columns {
+		index[c] = i
+	}
+	for _, repo := range msg.Workspace.Repos {
+		for _, task := range repo.Tasks {
+			i := index[task.StateIn(m.columns)]
+			m.cards[i] = append(m.cards[i], card{task: task, repo: repo})
+		}
+	}
+	m.find(selected.task.ID)
+}
+
+// setTask replaces a task that has changed, e.g. by being moved, and moves the
+// cursor along with it.
+func (m *boardModel) setTask(task models.Task) {
+	for i, repo := range m.workspace.Repos {
+		for j, t := range repo.Tasks {
+			if t.ID == task.ID {
+				m.workspace.Repos[i].Tasks[j] = task
+			}
+		}
+	}
+	m.setBoard(boardMsg{Title: m.title, Workspace: m.workspace, Repo: m.repo})
+}
+
+// find moves the cursor to the task with id, or keeps it within the cards if
+// there is no such task.
+func (m *boardModel) find(id uint) {
+	for i := range m.cards {
+		for j, c := range m.cards[i] {
+			if id != 0 && c.task.ID == id {
+				m.col, m.row = i, j
+				return
+			}
+		}
+	}
+	m.clamp()
+}
+
+func (m *boardModel) clamp() {
+	if m.col >= len(m.columns) {
+		m.col = len(m.columns) - 1
+	}
+	if m.col < 0 {
+		m.col = 0
+	}
+	if m.row >= len(m.cards[m.col]) {
+		m.row = len(m.cards[m.col]) - 1
+	}
+	if m.row < 0 {
+		m.row = 0
+	}
+}
+
+// selected returns the card under the cursor.
+func (m boardModel) selected() (card, bool) {
+	if m.col >= len(m.cards) || m.row >= len(m.cards[m.col]) {
+		return card{}, false
+	}
+	return m.cards[m.col][m.row], true
+}
+
+func (m boardModel) update(msg tea.Msg) (boardModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height, m.width = msg.Height, msg.Width
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.up):
+			m.row--
+			m.clamp()
+		case key.Matches(msg, m.keys.down):
+			m.row++
+			m.clamp()
+		case key.Matches(msg, m.keys.left):
+			m.col--
+			m.clamp()
+		case key.Matches(msg, m.keys.right):
+			m.col++
+			m.clamp()
+		case key.Matches(msg, m.keys.moveLeft):
+			if c, ok := m.selected(); ok && m.col > 0 {
+				return m, moveTaskCmd(c.task, c.repo, m.columns[m.col-1])
+			}
+		case key.Matches(msg, m.keys.moveRight):
+			if c, ok := m.selected(); ok && m.col < len(m.columns)-1 {
+				return m, moveTaskCmd(c.task, c.repo, m.columns[m.col+1])
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m boardModel) view() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", titleStyle.Render(m.title))
+
+	var total time.Duration
+	done := m.cards[len(m.cards)-1]
+	for _, c := range done {
+		d, _ := c.task.CycleTime()
+		total += d
+	}
+	if len(done) > 0 {
+		mean := (total / time.Duration(len(done))).Truncate(time.Minute)
+		b.WriteString(secondaryStyle.Render(fmt.Sprintf("Mean cycle time: %s over %d completed", models.ShortDuration(mean), len(done))))
+	} else {
+		b.WriteString(secondaryStyle.Render("No tasks have been completed yet"))
+	}
+	b.WriteString("\n\n")
+
+	// leave room for the title, the cycle time, the column headers and the help.
+	x, y := frameSize()
+	visible := intMax((m.height-y-8)/cardHeight, 1)
+	width := intMax((m.width-x)/len(m.columns)-2, 8)
+	columns := make([]string, len(m.columns))
+	for i := range m.columns {
+		columns[i] = lipgloss.NewStyle().Width(width).MarginRight(2).Render(m.column(i, width, visible))
+	}
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, columns...))
+
+	fmt.Fprintf(&b, "\n\n%s", m.help.View(m.keys))
+	return b.String()
+}
+
+// column draws the header and the cards of column i that fit, the column with
+// the cursor scrolls to keep it in view.
+func (m boardModel) column(i, width, visible int) string {
+	header := cut(fmt.Sprintf("%s (%d)", strings.Title(m.columns[i]), len(m.cards[i])), width-2)
+	if i == m.col {
+		header = focusedStyle.Copy().PaddingLeft(2).Render(header)
+	} else {
+		header = primaryStyle.Render(header)
+	}
+	lines := []string{header, ""}
+
+	first := 0
+	if i == m.col && m.row >= visible {
+		first = m.row - visible + 1
+	}
+	now := time.Now()
+	for j := first; j < len(m.cards[i]) && j < first+visible; j++ {
+		c := m.cards[i][j]
+		// the styles indent the cards by two characters.
+		name, details := cut(c.task.Name, width-2), cut(m.details(c, now), width-2)
+		if i == m.col && j == m.row {
+			lines = append(lines, primarySelectedStyle.Render(name), secondarySelectedStyle.Render(details), "")
+		} else {
+			lines = append(lines, primaryStyle.Render(name), secondaryStyle.Render(details), "")
+		}
+	}
+	if rest := len(m.cards[i]) - first - visible; rest > 0 {
+		lines = append(lines, secondaryDimmedStyle.Render(fmt.Sprintf("%d more", rest)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// details describes a card below the name of its task, with the cycle time of
+// completed tasks and the tracked time of the others.
+func (m boardModel) details(c card, now time.Time) string {
+	var parts []string
+	if m.repo == "" {
+		parts = append(parts, c.repo.Name)
+	}
+	tracked := fmt.Sprintf("%s / %s", models.ShortDuration(c.task.Tracked(now).Truncate(time.Minute)), models.ShortDuration(c.task.ExpectedDuration))
+	switch cycle, done := c.task.CycleTime(); {
+	case done:
+		parts = append(parts, fmt.Sprintf("took %s", models.ShortDuration(cycle.Truncate(time.Minute))))
+	case c.task.Running():
+		parts = append(parts, "▶ "+tracked)
+	default:
+		parts = append(parts, tracked)
+	}
+	return strings.Join(parts, " · ")
+}
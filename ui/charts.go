@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mellonnen/chronograph/models"
+)
+
+// blocks are the partial blocks used to draw bars with a resolution of an
+// eighth of a character, and the levels of a sparkline.
+var blocks = []rune("▏▎▍▌▋▊▉█")
+
+var levels = []rune("▁▂▃▄▅▆▇█")
+
+// maxLabelWidth is the widest a label gets in a bar chart before it is cut.
+const maxLabelWidth = 24
+
+// barChart draws a horizontal bar for each value, scaled so that the largest
+// value fills the width. The values are written after the bars by format.
+func barChart(labels []string, values []float64, format func(float64) string, width int) string {
+	if len(values) == 0 {
+		return secondaryStyle.Render("Nothing to show") + "\n"
+	}
+	labelWidth, valueWidth := 0, 0
+	max := 0.0
+	for i, v := range values {
+		labelWidth = intMax(labelWidth, len([]rune(cut(labels[i], maxLabelWidth))))
+		valueWidth = intMax(valueWidth, len([]rune(format(v))))
+		max = math.Max(max, v)
+	}
+	// leave room for the indentation and the spaces between the columns.
+	barWidth := intMax(width-labelWidth-valueWidth-6, 1)
+
+	var b strings.Builder
+	for i, v := range values {
+		label := fmt.Sprintf("%-*s", labelWidth, cut(labels[i], maxLabelWidth))
+		bar := ""
+		if max > 0 {
+			bar = drawBar(v / max * float64(barWidth))
+		}
+		fmt.Fprintf(&b, "%s  %s%s  %s\n",
+			secondaryStyle.Render(label),
+			chartStyle.Render(bar),
+			strings.Repeat(" ", barWidth-len([]rune(bar))),
+			secondaryStyle.Copy().Padding(0).Render(format(v)),
+		)
+	}
+	return b.String()
+}
+
+// drawBar draws a bar that is length characters long, in eighths.
+func drawBar(length float64) string {
+	eighths := int(math.Round(length * 8))
+	bar := strings.Repeat(string(blocks[len(blocks)-1]), eighths/8)
+	if rest := eighths % 8; rest > 0 {
+		bar += string(blocks[rest-1])
+	}
+	return bar
+}
+
+// sparkline draws a character for each value, as high as the value compared
+// to the largest one.
+func sparkline(values []time.Duration) string {
+	var max time.Duration
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		if v == 0 || max == 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		level := int(float64(v) / float64(max) * float64(len(levels)-1))
+		b.WriteRune(levels[level])
+	}
+	return chartStyle.Render(b.String())
+}
+
+// hoursValue formats a chart value that holds a duration.
+func hoursValue(v float64) string {
+	return models.ShortDuration(time.Duration(v).Truncate(time.Minute))
+}
+
+// countValue formats a chart value that holds a count.
+func countValue(v float64) string {
+	return fmt.Sprint(int(v))
+}
+
+// cut shortens s to at most n runes, marking that it was cut.
+func cut(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(append(r[:n-1], '…'))
+}
+
+func intMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -2,31 +2,263 @@ package ui
 
 import (
 	"fmt"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/export"
+	"github.com/mellonnen/chronograph/git"
 	"github.com/mellonnen/chronograph/models"
-	"gorm.io/driver/sqlite"
+	"github.com/mellonnen/chronograph/report"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/timesheet"
+	"github.com/mellonnen/chronograph/tracker"
 	"gorm.io/gorm"
 )
 
 func initSqliteCmd(dbPath string) tea.Cmd {
 	return func() tea.Msg {
-		db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+		s, err := store.Open(dbPath)
 		if err != nil {
 			return errorMsg(fmt.Errorf("initializing sqlite database: %w", err))
 		}
-		db.AutoMigrate(&models.Workspace{}, &models.Repo{}, &models.Task{})
-		return dbMsg{DB: db}
+		return storeMsg{Store: s}
 	}
 }
 
-func listWorkspacesCmd(db *gorm.DB) tea.Cmd {
+func storeCmd(s store.Store) tea.Cmd {
 	return func() tea.Msg {
-		var workspaces []models.Workspace
-		db.Find(&workspaces)
+		return storeMsg{Store: s}
+	}
+}
+
+func listWorkspacesCmd(s store.Store) tea.Cmd {
+	return func() tea.Msg {
+		workspaces, err := s.Workspaces()
+		if err != nil {
+			return errorMsg(err)
+		}
 		return listWorkspacesMsg{Workspaces: workspaces}
 	}
 }
+
+func listReposCmd(s store.Store, workspaceID uint) tea.Cmd {
+	return func() tea.Msg {
+		repos, err := s.Repos(workspaceID)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return listReposMsg{Repos: repos}
+	}
+}
+
+func listTasksCmd(s store.Store, repoID uint, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		tasks, err := s.Tasks(repoID)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return listTasksMsg{Tasks: tasks, BranchStatuses: branchStatuses(tasks, repoPath)}
+	}
+}
+
+// branchStatuses returns the status of the branch of each task by ID.
+func branchStatuses(tasks []models.Task, repoPath string) map[uint]string {
+	statuses := make(map[uint]string)
+	for _, t := range tasks {
+		// the repo might have moved, which should not keep the tasks from being listed.
+		if status, err := tracker.BranchStatus(t, repoPath); err == nil {
+			statuses[t.ID] = status
+		}
+	}
+	return statuses
+}
+
+// watchInterval is how often the TUI checks whether another process, like the
+// CLI or the server, changed something.
+const watchInterval = 2 * time.Second
+
+// revisionCmd fetches the revision of the store after the delay.
+func revisionCmd(s store.Store, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		revision, err := s.Revision()
+		return revisionMsg{Revision: revision, err: err}
+	})
+}
+
+// reloadCmd fetches the workspaces again, and the repos of the workspace and
+// the tasks of the repo unless their IDs are zero.
+func reloadCmd(s store.Store, workspaceID, repoID uint, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		msg := reloadedMsg{WorkspaceID: workspaceID, RepoID: repoID}
+		var err error
+		if msg.Workspaces, err = s.Workspaces(); err != nil {
+			return errorMsg(err)
+		}
+		if workspaceID != 0 {
+			if msg.Repos, err = s.Repos(workspaceID); err != nil {
+				return errorMsg(err)
+			}
+		}
+		if repoID != 0 {
+			if msg.Tasks, err = s.Tasks(repoID); err != nil {
+				return errorMsg(err)
+			}
+			msg.BranchStatuses = branchStatuses(msg.Tasks, repoPath)
+		}
+		return msg
+	}
+}
+
+func createWorkspaceCmd(s store.Store, workspace models.Workspace) tea.Cmd {
+	return func() tea.Msg {
+		if err := s.CreateWorkspace(&workspace); err != nil {
+			return errorMsg(err)
+		}
+		return addResourceMsg{Resource: workspace}
+	}
+}
+
+func createRepoCmd(s store.Store, repo models.Repo) tea.Cmd {
+	return func() tea.Msg {
+		if err := s.CreateRepo(&repo); err != nil {
+			return errorMsg(err)
+		}
+		return addResourceMsg{Resource: repo}
+	}
+}
+
+func createTaskCmd(s store.Store, task models.Task) tea.Cmd {
+	return func() tea.Msg {
+		err := s.Transaction(func(s store.Store) error {
+			if err := s.CreateTask(&task); err != nil {
+				return err
+			}
+			return s.SetTaskTags(&task)
+		})
+		if err != nil {
+			return errorMsg(err)
+		}
+		return addResourceMsg{Resource: task}
+	}
+}
+
+// archiveResourceCmd archives a resource, which hides it until it is restored.
+func archiveResourceCmd(s store.Store, resource models.Listable) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch r := resource.(type) {
+		case models.Workspace:
+			err = s.ArchiveWorkspace(r.ID)
+		case models.Repo:
+			err = s.ArchiveRepo(r.ID)
+		case models.Task:
+			err = s.ArchiveTask(r.ID)
+		}
+		if err != nil {
+			return errorMsg(err)
+		}
+		return removedResourceMsg{id: resource.GetID()}
+	}
+}
+
+// unarchiveResourceCmd restores an archived resource.
+func unarchiveResourceCmd(s store.Store, resource models.Listable) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch r := resource.(type) {
+		case models.Workspace:
+			err = s.RestoreWorkspace(r.ID)
+			r.DeletedAt = gorm.DeletedAt{}
+			resource = r
+		case models.Repo:
+			err = s.RestoreRepo(r.ID)
+			r.DeletedAt = gorm.DeletedAt{}
+			resource = r
+		case models.Task:
+			err = s.RestoreTask(r.ID)
+			r.DeletedAt = gorm.DeletedAt{}
+			resource = r
+		}
+		if err != nil {
+			return errorMsg(err)
+		}
+		return restoredResourceMsg{Resource: resource}
+	}
+}
+
+// listArchivedCmd fetches the archived resources of a type, parent is the id
+// of the workspace or repo they belong to.
+func listArchivedCmd(s store.Store, r Resource, parent uint) tea.Cmd {
+	return func() tea.Msg {
+		var resources []models.Listable
+		var err error
+		switch r {
+		case Workspace:
+			var workspaces []models.Workspace
+			workspaces, err = s.ArchivedWorkspaces()
+			resources = listables(workspaces)
+		case Repo:
+			var repos []models.Repo
+			repos, err = s.ArchivedRepos(parent)
+			resources = listables(repos)
+		case Task:
+			var tasks []models.Task
+			tasks, err = s.ArchivedTasks(parent)
+			resources = listables(tasks)
+		}
+		if err != nil {
+			return errorMsg(err)
+		}
+		return listArchivedMsg{Type: r, Resources: resources}
+	}
+}
+
+// listables converts a slice of resources to a slice of the interface.
+func listables[L models.Listable](resources []L) []models.Listable {
+	l := make([]models.Listable, len(resources))
+	for i, r := range resources {
+		l[i] = r
+	}
+	return l
+}
+
+// countChildrenCmd counts what would be removed along with a resource, so that
+// the removal can be confirmed.
+func countChildrenCmd(s store.Store, resource models.Listable) tea.Cmd {
+	return func() tea.Msg {
+		children, err := tracker.CountChildren(s, resource)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return confirmRemoveMsg{Resource: resource, Children: children}
+	}
+}
+
+func answerCmd(then tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		return answerMsg{then: then}
+	}
+}
+
+// deleteResourceCmd removes a resource and everything that belongs to it.
+func deleteResourceCmd(s store.Store, resource models.Listable) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch r := resource.(type) {
+		case models.Workspace:
+			err = s.DeleteWorkspace(r.ID)
+		case models.Repo:
+			err = s.DeleteRepo(r.ID)
+		case models.Task:
+			err = s.DeleteTask(r.ID)
+		}
+		if err != nil {
+			return errorMsg(err)
+		}
+		return removedResourceMsg{id: resource.GetID()}
+	}
+}
+
 func addWorkspaceCmd(workspace models.Workspace) tea.Cmd {
 	return func() tea.Msg {
 		return addWorkspaceMsg{Workspace: workspace}
@@ -50,23 +282,87 @@ func createResourceCmd() tea.Cmd {
 	}
 }
 
-func addResourceCmd(resource models.Listable) tea.Cmd {
+func removeResourceCmd(id uint) tea.Cmd {
 	return func() tea.Msg {
-		return addResourceMsg{
-			Resource: resource,
-		}
+		return removeResourceMsg{id: id}
+	}
+}
+
+func showArchivedCmd() tea.Cmd {
+	return func() tea.Msg {
+		return showArchivedMsg{}
+	}
+}
+
+func restoreResourceCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return restoreResourceMsg{id: id}
 	}
 }
 
-func removeResourceCmd(index int) tea.Cmd {
+func editResourceCmd(id uint) tea.Cmd {
 	return func() tea.Msg {
-		return removeResourceMsg{index: index}
+		return editResourceMsg{id: id}
 	}
 }
 
-func chooseResourceCmd(index int) tea.Cmd {
+func saveResourceCmd(resource models.Listable) tea.Cmd {
 	return func() tea.Msg {
-		return chooseResourceMsg{index: index}
+		return saveResourceMsg{Resource: resource}
+	}
+}
+
+// updateResourceCmd persists the changes to an edited resource. Only the
+// fields of the form are changed on the stored resource, which is read again
+// so that the timers and the board that changed it meanwhile are kept.
+func updateResourceCmd(s store.Store, resource models.Listable) tea.Cmd {
+	return func() tea.Msg {
+		err := s.Transaction(func(s store.Store) error {
+			switch edited := resource.(type) {
+			case models.Workspace:
+				r, err := s.Workspace(edited.ID)
+				if err != nil {
+					return err
+				}
+				r.Name, r.Description = edited.Name, edited.Description
+				resource = r
+				return s.UpdateWorkspace(&r)
+			case models.Repo:
+				r, err := s.Repo(edited.ID)
+				if err != nil {
+					return err
+				}
+				r.Name, r.Description = edited.Name, edited.Description
+				r.Path, r.Remote = edited.Path, edited.Remote
+				resource = r
+				return s.UpdateRepo(&r)
+			case models.Task:
+				r, err := s.Task(edited.ID)
+				if err != nil {
+					return err
+				}
+				r.Name, r.Description = edited.Name, edited.Description
+				r.ExpectedDuration, r.Tags = edited.ExpectedDuration, edited.Tags
+				if err := s.UpdateTask(&r); err != nil {
+					return err
+				}
+				if err := s.SetTaskTags(&r); err != nil {
+					return err
+				}
+				resource = r
+			}
+			return nil
+		})
+		if err != nil {
+			return errorMsg(err)
+		}
+		return updatedResourceMsg{Resource: resource}
+	}
+}
+
+func chooseResourceCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return chooseResourceMsg{id: id}
 	}
 }
 
@@ -75,3 +371,526 @@ func errorCmd(err error) tea.Cmd {
 		return errorMsg(err)
 	}
 }
+
+func startTaskCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return startTaskMsg{id: id}
+	}
+}
+
+// startOnBranchCmd starts the task after its branch has been offered.
+func startOnBranchCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return startTaskMsg{id: id, branchChecked: true}
+	}
+}
+
+// offerBranchCmd works out the branch of the task from the template and offers
+// to check it out, unless it already is.
+func offerBranchCmd(id uint, task models.Task, tmpl, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		branch := task.Branch
+		if branch == "" {
+			var err error
+			if branch, err = tracker.BranchName(tmpl, task); err != nil {
+				return errorMsg(err)
+			}
+		}
+		// a repo without a path has no branches, so the task starts right away.
+		if repoPath == "" {
+			return startTaskMsg{id: id, branchChecked: true}
+		}
+		current, err := git.CurrentBranch(repoPath)
+		if err != nil || current == branch {
+			return startTaskMsg{id: id, branchChecked: true}
+		}
+		exists, err := git.BranchExists(repoPath, branch)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return offerBranchMsg{id: id, Branch: branch, Current: current, Exists: exists}
+	}
+}
+
+// checkoutAndStartCmd checks out the branch of the task and starts it.
+func checkoutAndStartCmd(s store.Store, task models.Task, repoPath, branch string) tea.Cmd {
+	return func() tea.Msg {
+		task, paused, err := tracker.Switch(s, task, repoPath, branch)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return startedTaskMsg{Task: task, Paused: paused}
+	}
+}
+
+func startPomodoroCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return startPomodoroMsg{id: id}
+	}
+}
+
+func pauseTaskCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return pauseTaskMsg{id: id}
+	}
+}
+
+func annotateTaskCmd(id uint, note string) tea.Cmd {
+	return func() tea.Msg {
+		return annotateTaskMsg{id: id, note: note}
+	}
+}
+
+func completeTaskCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return completeTaskMsg{id: id}
+	}
+}
+
+// startTimerCmd opens a new time entry on the task, pausing the running ones.
+func startTimerCmd(s store.Store, task models.Task, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		task, paused, err := tracker.Switch(s, task, repoPath, "")
+		if err != nil {
+			return errorMsg(err)
+		}
+		return startedTaskMsg{Task: task, Paused: paused}
+	}
+}
+
+// startPomodoroTimerCmd opens a new time entry on the task that is a pomodoro,
+// pausing the running ones. The task is read again, as the pomodoro after a
+// break starts while it need not be shown.
+func startPomodoroTimerCmd(s store.Store, id uint, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		task, err := s.Task(id)
+		if err != nil {
+			return errorMsg(err)
+		}
+		task, paused, err := tracker.SwitchPomodoro(s, task, repoPath, "")
+		if err != nil {
+			return errorMsg(err)
+		}
+		return startedTaskMsg{Task: task, Paused: paused}
+	}
+}
+
+// finishPomodoroTimerCmd closes the pomodoro of the task at the time it was
+// due. The task is read again, as the pomodoros go on while it is not shown.
+func finishPomodoroTimerCmd(s store.Store, id uint, repoPath string, at time.Time) tea.Cmd {
+	return func() tea.Msg {
+		task, err := s.Task(id)
+		if err != nil {
+			return finishedPomodoroMsg{Task: task, err: err}
+		}
+		task, err = tracker.FinishPomodoro(s, task, repoPath, at)
+		return finishedPomodoroMsg{Task: task, err: err}
+	}
+}
+
+// pauseTimerCmd closes the open time entry of the task.
+func pauseTimerCmd(s store.Store, task models.Task, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		task, err := tracker.Pause(s, task, repoPath)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return updateTaskMsg{Task: task}
+	}
+}
+
+// completeTimerCmd closes any open time entry and marks the task as complete.
+func completeTimerCmd(s store.Store, task models.Task, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		task, err := tracker.Complete(s, task, repoPath)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return updateTaskMsg{Task: task}
+	}
+}
+
+// annotateEntryCmd sets the note of a time entry.
+func annotateEntryCmd(s store.Store, entry models.TimeEntry, note string) tea.Cmd {
+	return func() tea.Msg {
+		if err := tracker.Annotate(s, entry, note); err != nil {
+			return errorMsg(err)
+		}
+		task, err := s.Task(entry.TaskID)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return updateTaskMsg{Task: task}
+	}
+}
+
+// taskCommitsCmd lists the commits made while the task was worked on. Commits
+// linked to the task by the git hooks are listed when there are any, otherwise
+// the commits between the start SHA and the end SHA, or HEAD if the task is
+// not completed yet.
+func taskCommitsCmd(s store.Store, task models.Task, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		linked, err := s.TaskCommits(task.ID)
+		if err != nil {
+			return commitsMsg{id: task.ID, err: err}
+		}
+		if len(linked) > 0 {
+			commits := make([]git.Commit, len(linked))
+			shas := make([]string, len(linked))
+			for i, c := range linked {
+				commits[i] = git.Commit{SHA: c.SHA, Author: c.Author, Time: c.CommittedAt, Subject: c.Subject}
+				shas[i] = c.SHA
+			}
+			// the commits of an imported repo without a path cannot be diffed.
+			if repoPath == "" {
+				return commitsMsg{id: task.ID, Commits: commits}
+			}
+			stat, err := git.ShortStatOf(repoPath, shas)
+			if err != nil {
+				return commitsMsg{id: task.ID, err: err}
+			}
+			return commitsMsg{id: task.ID, Commits: commits, Stat: stat}
+		}
+
+		if repoPath == "" {
+			return commitsMsg{id: task.ID}
+		}
+		from, to := string(task.StartSHA), string(task.EndSHA)
+		if to == "" {
+			to = "HEAD"
+		}
+		commits, err := git.CommitsInRange(repoPath, from, to)
+		if err != nil {
+			return commitsMsg{id: task.ID, err: err}
+		}
+		stat, err := git.ShortStatInRange(repoPath, from, to)
+		if err != nil {
+			return commitsMsg{id: task.ID, err: err}
+		}
+		return commitsMsg{id: task.ID, Commits: commits, Stat: stat}
+	}
+}
+
+func reportResourceCmd(id uint, archived bool) tea.Cmd {
+	return func() tea.Msg {
+		return reportResourceMsg{id: id, archived: archived}
+	}
+}
+
+// reportCmd builds the estimate-vs-actual report of a workspace, optionally
+// including its archived repos and tasks.
+func reportCmd(s store.Store, workspace models.Workspace, archived bool) tea.Cmd {
+	return func() tea.Msg {
+		workspace, err := tracker.WorkspaceTree(s, workspace, archived)
+		if err != nil {
+			return errorMsg(err)
+		}
+		title := fmt.Sprintf("%s estimates", workspace.Name)
+		if archived {
+			title += " (including archived)"
+		}
+		return reportMsg{
+			id:       workspace.ID,
+			archived: archived,
+			Title:    title,
+			Report:   report.New(workspace, time.Now()),
+		}
+	}
+}
+
+func exportResourceCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return exportResourceMsg{id: id}
+	}
+}
+
+func saveExportCmd(path string, format export.Format, filter export.Filter) tea.Cmd {
+	return func() tea.Msg {
+		return saveExportMsg{Path: path, Format: format, Filter: filter}
+	}
+}
+
+// exportCmd writes the sessions selected by the filter to a file.
+func exportCmd(s store.Store, path string, format export.Format, filter export.Filter) tea.Cmd {
+	return func() tea.Msg {
+		now := time.Now()
+		workspaces, err := export.Collect(s, filter, now)
+		if err != nil {
+			return errorMsg(err)
+		}
+		if err := export.WriteFile(path, format, workspaces, now); err != nil {
+			return errorMsg(err)
+		}
+		return exportedMsg{Path: path}
+	}
+}
+
+func inferResourceCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return inferResourceMsg{id: id}
+	}
+}
+
+func inferRangeCmd(id uint, from, to time.Time) tea.Cmd {
+	return func() tea.Msg {
+		return inferRangeMsg{id: id, From: from, To: to}
+	}
+}
+
+// inferCmd proposes sessions for the untracked commits of the repo between from
+// and to, an open end of the range is now.
+func inferCmd(s store.Store, repo models.Repo, from, to time.Time, gap, leadIn time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		if to.IsZero() {
+			to = time.Now()
+		}
+		proposals, err := tracker.Infer(s, repo, from, to, gap, leadIn)
+		if err != nil {
+			return errorMsg(fmt.Errorf("inferring sessions of %s: %w", repo.Name, err))
+		}
+		tasks, err := s.Tasks(repo.ID)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return inferredMsg{Repo: repo, From: from, To: to, Proposals: proposals, Tasks: tasks}
+	}
+}
+
+func acceptProposalCmd(index int) tea.Cmd {
+	return func() tea.Msg {
+		return acceptProposalMsg{index: index}
+	}
+}
+
+// acceptCmd stores an inferred session as a time entry of its task.
+func acceptCmd(s store.Store, index int, proposal tracker.Proposal, repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		task, err := tracker.Accept(s, proposal, repoPath)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return acceptedProposalMsg{index: index, Task: task}
+	}
+}
+
+func editProposalCmd(index int) tea.Cmd {
+	return func() tea.Msg {
+		return editProposalMsg{index: index}
+	}
+}
+
+func saveProposalCmd(index int, task string, start, end time.Time) tea.Cmd {
+	return func() tea.Msg {
+		return saveProposalMsg{index: index, Task: task, Start: start, End: end}
+	}
+}
+
+func statsResourceCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return statsResourceMsg{id: id}
+	}
+}
+
+// statsCmd loads the repos, tasks and time entries of the workspace to draw
+// statistics of, only the repo named repo is kept if it is not empty.
+func statsCmd(s store.Store, workspace models.Workspace, repo string) tea.Cmd {
+	return func() tea.Msg {
+		workspace, err := workspaceTree(s, workspace, repo)
+		if err != nil {
+			return errorMsg(err)
+		}
+		if repo == "" {
+			return statsMsg{Title: fmt.Sprintf("Statistics of %s", workspace.Name), Workspace: workspace}
+		}
+		return statsMsg{Title: fmt.Sprintf("Statistics of %s", repo), Workspace: workspace, Repo: true}
+	}
+}
+
+func boardResourceCmd(id uint) tea.Cmd {
+	return func() tea.Msg {
+		return boardResourceMsg{id: id}
+	}
+}
+
+// boardCmd loads the tasks of the workspace to show on the board, only the
+// tasks of the repo named repo are kept if it is not empty.
+func boardCmd(s store.Store, workspace models.Workspace, repo string) tea.Cmd {
+	return func() tea.Msg {
+		workspace, err := workspaceTree(s, workspace, repo)
+		if err != nil {
+			return errorMsg(err)
+		}
+		title := fmt.Sprintf("Board of %s", workspace.Name)
+		if repo != "" {
+			title = fmt.Sprintf("Board of %s", repo)
+		}
+		return boardMsg{Title: title, Workspace: workspace, Repo: repo}
+	}
+}
+
+// workspaceTree loads the repos, tasks and time entries of the workspace, only
+// the repo named repo is kept if it is not empty.
+func workspaceTree(s store.Store, workspace models.Workspace, repo string) (models.Workspace, error) {
+	workspace, err := tracker.WorkspaceTree(s, workspace, false)
+	if err != nil || repo == "" {
+		return workspace, err
+	}
+	repos := workspace.Repos
+	workspace.Repos = nil
+	for _, r := range repos {
+		if r.Name == repo {
+			workspace.Repos = append(workspace.Repos, r)
+		}
+	}
+	return workspace, nil
+}
+
+func moveTaskCmd(task models.Task, repo models.Repo, to string) tea.Cmd {
+	return func() tea.Msg {
+		return moveTaskMsg{Task: task, Repo: repo, To: to}
+	}
+}
+
+// moveToColumnCmd moves a task to another column of the board.
+func moveToColumnCmd(s store.Store, task models.Task, repoPath string, columns []string, to string) tea.Cmd {
+	return func() tea.Msg {
+		task, err := tracker.Move(s, task, repoPath, columns, to)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return movedTaskMsg{Task: task}
+	}
+}
+
+func showTimesheetCmd() tea.Cmd {
+	return func() tea.Msg {
+		return showTimesheetMsg{}
+	}
+}
+
+func loadTimesheetCmd(from time.Time, week bool) tea.Cmd {
+	return func() tea.Msg {
+		return loadTimesheetMsg{From: from, Week: week}
+	}
+}
+
+// timesheetCmd lays out the time logged in all workspaces over the week or the
+// day starting at from.
+func timesheetCmd(s store.Store, from time.Time, week bool) tea.Cmd {
+	return func() tea.Msg {
+		workspaces, err := s.Workspaces()
+		if err != nil {
+			return errorMsg(err)
+		}
+		for i, ws := range workspaces {
+			if workspaces[i], err = tracker.WorkspaceTree(s, ws, false); err != nil {
+				return errorMsg(err)
+			}
+		}
+		days := 1
+		if week {
+			days = 7
+		}
+		return timesheetMsg{Sheet: timesheet.New(workspaces, from, days, time.Now()), Week: week}
+	}
+}
+
+func logTimeCmd(task models.Task, day time.Time) tea.Cmd {
+	return func() tea.Msg {
+		return logTimeMsg{Task: task, Day: day}
+	}
+}
+
+func saveEntryCmd(start, end time.Time, note string) tea.Cmd {
+	return func() tea.Msg {
+		return saveEntryMsg{Start: start, End: end, Note: note}
+	}
+}
+
+// logEntryCmd adds a closed time entry to the task.
+func logEntryCmd(s store.Store, task models.Task, start, end time.Time, note string) tea.Cmd {
+	return func() tea.Msg {
+		task, err := tracker.Log(s, task, start, end, note)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return loggedTimeMsg{Task: task}
+	}
+}
+
+func updateTaskCmd(task models.Task) tea.Cmd {
+	return func() tea.Msg {
+		return updateTaskMsg{Task: task}
+	}
+}
+
+// idleCheckInterval is how often the TUI checks whether the user went idle.
+const idleCheckInterval = 30 * time.Second
+
+func idleTickCmd() tea.Cmd {
+	return tea.Tick(idleCheckInterval, func(time.Time) tea.Msg {
+		return idleTickMsg{}
+	})
+}
+
+// checkIdleCmd looks for a running task that saw no activity for the timeout.
+// The last key press, the last change to the files of its repo and the start
+// of its session all count as activity.
+func checkIdleCmd(s store.Store, lastInput time.Time, timeout time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		tasks, err := s.RunningTasks()
+		if err != nil {
+			return errorMsg(err)
+		}
+		for _, task := range tasks {
+			repo, err := s.Repo(task.RepoID)
+			if err != nil {
+				return errorMsg(err)
+			}
+			since := lastInput
+			// an imported repo without a path has no files to watch.
+			if repo.Path != "" {
+				if changed, err := git.LastChange(repo.Path); err == nil && changed.After(since) {
+					since = changed
+				}
+			}
+			if entry := task.ActiveEntry(); entry != nil && entry.StartedAt.After(since) {
+				since = entry.StartedAt
+			}
+			if time.Since(since) >= timeout {
+				return idleMsg{Task: task, Repo: repo, Since: since}
+			}
+		}
+		return nil
+	}
+}
+
+// trimIdleCmd ends the session of the task when the user went idle and starts
+// a new one, keeping the idle time as a session of its own if keepIdle is set.
+func trimIdleCmd(s store.Store, task models.Task, repoPath string, since time.Time, keepIdle bool) tea.Cmd {
+	return func() tea.Msg {
+		task, err := tracker.TrimIdle(s, task, repoPath, since, keepIdle)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return trimmedIdleMsg{Task: task}
+	}
+}
+
+func backCmd() tea.Cmd {
+	return func() tea.Msg {
+		return backMsg{}
+	}
+}
+
+func pomodoroTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return pomodoroTickMsg{Time: t}
+	})
+}
+
+func tickCmd(id int) tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg{id: id, Time: t}
+	})
+}
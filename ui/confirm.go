@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// confirmModel asks a question, e.g. whether an archived resource should be
+// purged for good. Any answer returns to the previous screen and runs the
+// command of the answer.
+type confirmModel struct {
+	crumb    string
+	question string
+	details  string
+	choices  []choice
+
+	help help.Model
+}
+
+// choice is an answer to a confirmation.
+type choice struct {
+	key  key.Binding
+	then tea.Cmd
+}
+
+type confirmKeyMap []choice
+
+func (k confirmKeyMap) ShortHelp() []key.Binding {
+	bindings := make([]key.Binding, len(k))
+	for i, c := range k {
+		bindings[i] = c.key
+	}
+	return bindings
+}
+
+func (k confirmKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newConfirm creates a yes or no question, crumb names it in the breadcrumbs
+// and action describes what answering yes does.
+func newConfirm(crumb, question, details, action string, yes, no tea.Cmd) confirmModel {
+	return newChoice(crumb, question, details,
+		choice{key.NewBinding(key.WithKeys("y"), key.WithHelp("y", action)), yes},
+		choice{key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "no")), no},
+	)
+}
+
+// newChoice creates a question with any number of answers.
+func newChoice(crumb, question, details string, choices ...choice) confirmModel {
+	return confirmModel{
+		crumb:    crumb,
+		question: question,
+		details:  details,
+		choices:  choices,
+		help:     help.New(),
+	}
+}
+
+func (m confirmModel) update(msg tea.Msg) (confirmModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		for _, c := range m.choices {
+			if key.Matches(msg, c.key) {
+				return m, answerCmd(c.then)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m confirmModel) view() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n%s\n\n", titleStyle.Render(m.question), m.details)
+	b.WriteString(m.help.View(confirmKeyMap(m.choices)))
+	b.WriteString(helpStyle.Render(" • esc cancel"))
+	return b.String()
+}
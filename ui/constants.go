@@ -6,4 +6,12 @@ const (
 	Workspace Resource = "workspace"
 	Repo      Resource = "repo"
 	Task      Resource = "task"
+	// Export is not stored, but exporting is done through a form like the resources.
+	Export Resource = "export"
+	// Infer and Session are forms for inferring sessions from git activity and
+	// for adjusting the inferred sessions before they are stored.
+	Infer   Resource = "infer"
+	Session Resource = "session"
+	// Entry is a form for logging time from the timesheet.
+	Entry Resource = "entry"
 )
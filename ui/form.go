@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,20 +12,24 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/export"
 	"github.com/mellonnen/chronograph/git"
 	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/tracker"
 )
 
+// The form styles are built by applyTheme.
 var (
-	focusedStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-	blurredStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	cursorStyle         = focusedStyle.Copy()
-	noStyle             = lipgloss.NewStyle()
-	helpStyle           = blurredStyle.Copy()
-	cursorModeHelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-
-	focusedButton = focusedStyle.Copy().Render("[ Submit ]")
-	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Submit"))
+	focusedStyle        lipgloss.Style
+	blurredStyle        lipgloss.Style
+	cursorStyle         lipgloss.Style
+	noStyle             lipgloss.Style
+	helpStyle           lipgloss.Style
+	cursorModeHelpStyle lipgloss.Style
+
+	focusedButton string
+	blurredButton string
 )
 
 type formModel struct {
@@ -35,6 +40,16 @@ type formModel struct {
 	keys     formKeyMap
 	resource Resource
 	title    string
+
+	// original is the resource being edited, nil when creating a new one.
+	original models.Listable
+	// repoID is the repo an infer form infers the sessions of.
+	repoID uint
+	// index is the inferred session a session form adjusts.
+	index int
+
+	// filter selects what an export form exports.
+	filter export.Filter
 }
 
 type formKeyMap struct {
@@ -49,7 +64,7 @@ func newFormKeyMap() formKeyMap {
 	}
 }
 
-func newForm(r Resource) formModel {
+func newForm(r Resource, cfg config.Config) formModel {
 	m := formModel{}
 	m.resource = r
 	m.keys = newFormKeyMap()
@@ -74,12 +89,168 @@ func newForm(r Resource) formModel {
 			}
 			return true
 		}
-		m.inputs = append(m.inputs, newInput("Estimated time", validate))
+		estimate := newInput("Estimated time", validate)
+		if cfg.DefaultEstimate > 0 {
+			estimate.Input.SetValue(models.ShortDuration(cfg.DefaultEstimate))
+		}
+		m.inputs = append(m.inputs, estimate)
+		m.inputs = append(m.inputs, newInput("Tags (comma separated, optional)"))
+	}
+
+	m.focusFirst()
+	return m
+}
+
+// newExportForm creates a form that asks where to export the sessions selected
+// by filter to, and for which dates. The format follows the file extension.
+func newExportForm(filter export.Filter) formModel {
+	m := formModel{}
+	m.resource = Export
+	m.keys = newFormKeyMap()
+	m.filter = filter
+	name := filter.Workspace
+	if filter.Repo != "" {
+		name = filter.Repo
+	}
+	m.title = fmt.Sprintf("Export %s", name)
+
+	validDate := func(s string) bool {
+		_, err := export.ParseDate(s)
+		return err == nil
+	}
+	file := newInput("File (.csv, .json or .ics)", func(s string) bool {
+		_, err := export.FormatFromPath(s)
+		return err == nil
+	})
+	cwd, _ := os.Getwd()
+	file.Input.SetValue(filepath.Join(cwd, fmt.Sprintf("chronograph-%s.csv", name)))
+	file.Input.CursorEnd()
+
+	m.inputs = []inputModel{
+		file,
+		newInput("From date (YYYY-MM-DD, optional)", validDate),
+		newInput("To date (YYYY-MM-DD, optional)", validDate),
+	}
+	m.focusFirst()
+	return m
+}
+
+// newInferForm creates a form that asks for the dates to infer sessions of the
+// repo for, the last week by default.
+func newInferForm(repo models.Repo) formModel {
+	m := formModel{}
+	m.resource = Infer
+	m.keys = newFormKeyMap()
+	m.repoID = repo.ID
+	m.title = fmt.Sprintf("Infer sessions of %s", repo.Name)
+
+	validDate := func(s string) bool {
+		_, err := export.ParseDate(s)
+		return err == nil
+	}
+	now := time.Now()
+	from := newInput("From date (YYYY-MM-DD, optional)", validDate)
+	from.Input.SetValue(now.AddDate(0, 0, -7).Format("2006-01-02"))
+	to := newInput("To date (YYYY-MM-DD, optional)", validDate)
+	to.Input.SetValue(now.Format("2006-01-02"))
+
+	m.inputs = []inputModel{from, to}
+	for i := range m.inputs {
+		m.inputs[i].Input.CursorEnd()
+	}
+	m.focusFirst()
+	return m
+}
+
+// sessionLayout is how the start and end of sessions are entered.
+const sessionLayout = "2006-01-02 15:04"
+
+// newSessionForm creates a form that adjusts the task and the times of the
+// inferred session at index.
+func newSessionForm(index int, p tracker.Proposal, tasks []models.Task) formModel {
+	m := formModel{}
+	m.resource = Session
+	m.keys = newFormKeyMap()
+	m.index = index
+	m.title = "Edit session"
+
+	names := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		names[t.Name] = true
+	}
+	validTime := func(s string) bool {
+		_, err := time.ParseInLocation(sessionLayout, s, time.Local)
+		return err == nil
 	}
+	task := newInput("Task", func(s string) bool { return names[s] })
+	task.Input.SetValue(p.Task.Name)
+	start := newInput("Start (YYYY-MM-DD HH:MM)", validTime)
+	start.Input.SetValue(p.Start.Format(sessionLayout))
+	end := newInput("End (YYYY-MM-DD HH:MM)", validTime)
+	end.Input.SetValue(p.End.Format(sessionLayout))
 
+	m.inputs = []inputModel{task, start, end}
+	for i := range m.inputs {
+		m.inputs[i].Input.CursorEnd()
+	}
+	m.focusFirst()
+	return m
+}
+
+// newEntryForm creates a form that logs time on the task on day.
+func newEntryForm(task models.Task, day time.Time) formModel {
+	m := formModel{}
+	m.resource = Entry
+	m.keys = newFormKeyMap()
+	m.title = fmt.Sprintf("Log time on %s", task.Name)
+
+	start := newInput("Start (YYYY-MM-DD HH:MM)", func(s string) bool {
+		_, err := time.ParseInLocation(sessionLayout, s, time.Local)
+		return err == nil
+	})
+	start.Input.SetValue(day.Add(9 * time.Hour).Format(sessionLayout))
+	duration := newInput("Duration", func(s string) bool {
+		d, err := time.ParseDuration(s)
+		return err == nil && d > 0
+	})
+	duration.Input.SetValue("1h")
+
+	m.inputs = []inputModel{start, duration, newInput("Note (optional)")}
+	for i := range m.inputs {
+		m.inputs[i].Input.CursorEnd()
+	}
+	m.focusFirst()
+	return m
+}
+
+// focusFirst focuses the first input of the form.
+func (m *formModel) focusFirst() {
 	m.inputs[0].Input.Focus()
 	m.inputs[0].Input.PromptStyle = focusedStyle
 	m.inputs[0].Input.TextStyle = focusedStyle
+}
+
+// newEditForm creates a form pre-filled with the values of an existing resource.
+func newEditForm(r Resource, cfg config.Config, original models.Listable) formModel {
+	m := newForm(r, cfg)
+	m.title = strings.Title(fmt.Sprintf("edit %s", r))
+	m.original = original
+
+	m.inputs[0].Input.SetValue(original.GetName())
+	switch o := original.(type) {
+	case models.Workspace:
+		m.inputs[1].Input.SetValue(o.Description.String)
+	case models.Repo:
+		m.inputs[1].Input.SetValue(o.Description.String)
+		m.inputs[2].Input.SetValue(o.Path)
+	case models.Task:
+		m.inputs[1].Input.SetValue(o.Description.String)
+		m.inputs[2].Input.SetValue(models.ShortDuration(o.ExpectedDuration))
+		m.inputs[3].Input.SetValue(strings.Join(o.TagNames(), ", "))
+	}
+	for i := range m.inputs {
+		m.inputs[i].Input.CursorEnd()
+	}
 	return m
 }
 
@@ -96,46 +267,16 @@ func (m formModel) update(msg tea.Msg) (formModel, tea.Cmd) {
 			if msg.String() == "enter" && m.focusIndex == len(m.inputs) {
 				// check for invalid fields.
 				valid := true
-				for _, input := range m.inputs {
-					if input.valid != nil && !*input.valid {
+				for i, input := range m.inputs {
+					if !input.validate(input.Input.Value()) {
+						m.inputs[i].valid = boolPtr(false)
 						valid = false
 					}
 				}
 				if !valid {
 					break
 				}
-				name := m.inputs[0].Input.Value()
-				desc := m.inputs[1].Input.Value()
-				switch m.resource {
-				case Workspace:
-					workspace := models.Workspace{
-						Name:        name,
-						Description: sql.NullString{String: desc, Valid: len(desc) > 0},
-					}
-					return m, addWorkspaceCmd(workspace)
-				case Repo:
-					path := m.inputs[2].Input.Value()
-					remote, err := git.RemoteFromPath(path)
-					if err != nil {
-						return m, errorCmd(fmt.Errorf("getting remote: %v", err))
-					}
-					repo := models.Repo{
-						Name:        name,
-						Description: sql.NullString{String: desc, Valid: len(desc) > 0},
-						Path:        path,
-						Remote:      remote,
-					}
-					return m, addRepoCmd(repo)
-				case Task:
-					// we can skip error handling here as we have validated thi input.
-					d, _ := time.ParseDuration(m.inputs[2].Input.Value())
-					task := models.Task{
-						Name:             name,
-						Description:      sql.NullString{String: desc, Valid: len(desc) > 0},
-						ExpectedDuration: d,
-					}
-					return m, addTaskCmd(task)
-				}
+				return m, m.submit()
 			}
 
 			if key.Matches(msg, m.keys.prev) {
@@ -177,6 +318,81 @@ func (m formModel) update(msg tea.Msg) (formModel, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// submit builds the resource from the inputs. New resources are added, while
+// edited ones keep the fields of the original that are not part of the form.
+func (m formModel) submit() tea.Cmd {
+	name := m.inputs[0].Input.Value()
+	desc := sql.NullString{String: m.inputs[1].Input.Value(), Valid: len(m.inputs[1].Input.Value()) > 0}
+	switch m.resource {
+	case Workspace:
+		workspace, _ := m.original.(models.Workspace)
+		workspace.Name = name
+		workspace.Description = desc
+		if m.original != nil {
+			return saveResourceCmd(workspace)
+		}
+		return addWorkspaceCmd(workspace)
+	case Repo:
+		path := m.inputs[2].Input.Value()
+		remote, err := git.RemoteFromPath(path)
+		if err != nil {
+			return errorCmd(fmt.Errorf("getting remote: %v", err))
+		}
+		repo, _ := m.original.(models.Repo)
+		repo.Name = name
+		repo.Description = desc
+		repo.Path = path
+		repo.Remote = remote
+		if m.original != nil {
+			return saveResourceCmd(repo)
+		}
+		return addRepoCmd(repo)
+	case Task:
+		// we can skip error handling here as we have validated thi input.
+		d, _ := time.ParseDuration(m.inputs[2].Input.Value())
+		task, _ := m.original.(models.Task)
+		task.Name = name
+		task.Description = desc
+		task.ExpectedDuration = d
+		task.Tags = models.ParseTags(m.inputs[3].Input.Value())
+		if m.original != nil {
+			return saveResourceCmd(task)
+		}
+		return addTaskCmd(task)
+	case Export:
+		path := config.ExpandPath(m.inputs[0].Input.Value())
+		// we can skip error handling here as we have validated the inputs.
+		format, _ := export.FormatFromPath(path)
+		filter := m.filter
+		var err error
+		filter.From, filter.To, err = export.ParseRange(m.inputs[1].Input.Value(), m.inputs[2].Input.Value())
+		if err != nil {
+			return errorCmd(err)
+		}
+		return saveExportCmd(path, format, filter)
+	case Infer:
+		from, to, err := export.ParseRange(m.inputs[0].Input.Value(), m.inputs[1].Input.Value())
+		if err != nil {
+			return errorCmd(err)
+		}
+		return inferRangeCmd(m.repoID, from, to)
+	case Session:
+		// we can skip error handling here as we have validated the inputs.
+		start, _ := time.ParseInLocation(sessionLayout, m.inputs[1].Input.Value(), time.Local)
+		end, _ := time.ParseInLocation(sessionLayout, m.inputs[2].Input.Value(), time.Local)
+		if !start.Before(end) {
+			return errorCmd(fmt.Errorf("the session must end after %s", start.Format(sessionLayout)))
+		}
+		return saveProposalCmd(m.index, m.inputs[0].Input.Value(), start, end)
+	case Entry:
+		// we can skip error handling here as we have validated the inputs.
+		start, _ := time.ParseInLocation(sessionLayout, m.inputs[0].Input.Value(), time.Local)
+		d, _ := time.ParseDuration(m.inputs[1].Input.Value())
+		return saveEntryCmd(start, start.Add(d), m.inputs[2].Input.Value())
+	}
+	return nil
+}
+
 func (m formModel) view() string {
 	var b strings.Builder
 
@@ -194,6 +410,7 @@ func (m formModel) view() string {
 		button = &focusedButton
 	}
 	fmt.Fprintf(&b, "\n\n%s\n\n", *button)
+	b.WriteString(helpStyle.Render("esc to cancel"))
 
 	return b.String()
 }
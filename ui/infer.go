@@ -0,0 +1,216 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/tracker"
+)
+
+// decision is what the user made of an inferred session.
+type decision int
+
+const (
+	pending decision = iota
+	accepted
+	discarded
+)
+
+func (d decision) String() string {
+	switch d {
+	case accepted:
+		return "accepted"
+	case discarded:
+		return "discarded"
+	default:
+		return "pending"
+	}
+}
+
+// inferModel lists the sessions inferred from the commits of a repo, which
+// can be accepted as time entries, edited or discarded one by one.
+type inferModel struct {
+	repo      models.Repo
+	title     string
+	tasks     []models.Task
+	proposals []tracker.Proposal
+	decisions []decision
+	cursor    int
+	height    int
+
+	keys inferKeyMap
+	help help.Model
+}
+
+type inferKeyMap struct {
+	up      key.Binding
+	down    key.Binding
+	accept  key.Binding
+	edit    key.Binding
+	discard key.Binding
+	back    key.Binding
+}
+
+func (k inferKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.up, k.down, k.accept, k.edit, k.discard, k.back}
+}
+
+func (k inferKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+func newInfer(msg inferredMsg, keys config.Keys, height int) inferModel {
+	return inferModel{
+		repo: msg.Repo,
+		title: fmt.Sprintf("Sessions of %s from %s to %s", msg.Repo.Name,
+			msg.From.Format("2006-01-02"), msg.To.Format("2006-01-02")),
+		tasks:     msg.Tasks,
+		proposals: msg.Proposals,
+		decisions: make([]decision, len(msg.Proposals)),
+		height:    height,
+		keys: inferKeyMap{
+			up:      newBinding(keys.Up, "up"),
+			down:    newBinding(keys.Down, "down"),
+			accept:  newBinding(keys.Choose, "accept"),
+			edit:    newBinding(keys.Edit, "edit"),
+			discard: newBinding(keys.Remove, "discard"),
+			back:    newBinding(keys.Back, "back"),
+		},
+		help: help.New(),
+	}
+}
+
+func (m inferModel) update(msg tea.Msg) (inferModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		if len(m.proposals) == 0 {
+			break
+		}
+		switch {
+		case key.Matches(msg, m.keys.up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case key.Matches(msg, m.keys.down):
+			if m.cursor < len(m.proposals)-1 {
+				m.cursor++
+			}
+		case key.Matches(msg, m.keys.accept):
+			if m.decisions[m.cursor] != accepted {
+				return m, acceptProposalCmd(m.cursor)
+			}
+		case key.Matches(msg, m.keys.edit):
+			if m.decisions[m.cursor] != accepted {
+				return m, editProposalCmd(m.cursor)
+			}
+		case key.Matches(msg, m.keys.discard):
+			if m.decisions[m.cursor] == pending {
+				m.decisions[m.cursor] = discarded
+				m.next()
+			}
+		}
+	case acceptedProposalMsg:
+		m.decisions[msg.index] = accepted
+		// the task may have been marked as started, which the other sessions should know.
+		for i := range m.proposals {
+			if m.proposals[i].Task.ID == msg.Task.ID {
+				m.proposals[i].Task = msg.Task
+			}
+		}
+		for i := range m.tasks {
+			if m.tasks[i].ID == msg.Task.ID {
+				m.tasks[i] = msg.Task
+			}
+		}
+		m.next()
+	}
+	return m, nil
+}
+
+// next moves the cursor to the next pending session, if there is one.
+func (m *inferModel) next() {
+	for i := m.cursor + 1; i < len(m.proposals); i++ {
+		if m.decisions[i] == pending {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// edit adjusts the session at index, the task is looked up by name.
+func (m *inferModel) edit(msg saveProposalMsg) {
+	for _, t := range m.tasks {
+		if t.Name == msg.Task {
+			m.proposals[msg.index].Task = t
+		}
+	}
+	m.proposals[msg.index].Start = msg.Start
+	m.proposals[msg.index].End = msg.End
+	m.decisions[msg.index] = pending
+}
+
+func (m inferModel) view() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", titleStyle.Render(m.title))
+	if len(m.proposals) == 0 {
+		b.WriteString(secondaryStyle.Render("No untracked commits were found in this period"))
+		fmt.Fprintf(&b, "\n\n%s", m.help.View(m.keys))
+		return b.String()
+	}
+
+	// only the sessions around the cursor fit, next to the title, the
+	// commits of the selected session and the help.
+	_, y := frameSize()
+	rows := m.height - y - 12
+	if rows < 1 {
+		rows = 1
+	}
+	first := 0
+	if m.cursor >= rows {
+		first = m.cursor - rows + 1
+	}
+	for i := first; i < len(m.proposals) && i < first+rows; i++ {
+		p := m.proposals[i]
+		line := fmt.Sprintf("%-9s %s–%s  %-6s  %-3d commits  %s",
+			m.decisions[i],
+			p.Start.Format(sessionLayout),
+			p.End.Format("15:04"),
+			models.ShortDuration(p.Duration().Truncate(time.Minute)),
+			len(p.Commits),
+			p.Task.Name,
+		)
+		switch {
+		case i == m.cursor:
+			b.WriteString(primarySelectedStyle.Render(line))
+		case m.decisions[i] == pending:
+			b.WriteString(primaryStyle.Render(line))
+		default:
+			b.WriteString(secondaryDimmedStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(primaryStyle.Render("Commits:"))
+	b.WriteString("\n")
+	commits := m.proposals[m.cursor].Commits
+	for i, c := range commits {
+		if i == 5 {
+			b.WriteString(secondaryStyle.Render(fmt.Sprintf("and %d more", len(commits)-i)))
+			b.WriteString("\n")
+			break
+		}
+		b.WriteString(secondaryStyle.Render(fmt.Sprintf("%s %s %s", c.Time.Format("15:04"), c.ShortSHA(), c.Subject)))
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\n%s", m.help.View(m.keys))
+	return b.String()
+}
@@ -7,17 +7,54 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/config"
 	"github.com/mellonnen/chronograph/models"
 )
 
 // item wraps a listable resource to satisfy the list.Item interface.
 type item struct {
 	models.Listable
+	// branchStatus tells whether the branch of a task has been merged.
+	branchStatus string
 }
 
-func (i item) Title() string       { return i.GetName() }
-func (i item) FilterValue() string { return i.GetName() }
-func (i item) Description() string { return i.GetDescription() }
+func (i item) Title() string { return i.GetName() }
+
+// tagSeparator separates the name of a task from its tags in the value the
+// list filters on.
+const tagSeparator = "\n"
+
+// FilterValue holds the name of the resource, followed by the tags of a task,
+// which tagFilter tells apart.
+func (i item) FilterValue() string {
+	task, ok := i.Listable.(models.Task)
+	if !ok {
+		return i.GetName()
+	}
+	return task.Name + tagSeparator + strings.Join(task.TagNames(), " ")
+}
+
+func (i item) Description() string {
+	task, ok := i.Listable.(models.Task)
+	if !ok {
+		return i.GetDescription()
+	}
+	var parts []string
+	if task.Branch != "" {
+		branch := task.Branch
+		if i.branchStatus != "" {
+			branch = fmt.Sprintf("%s (%s)", branch, i.branchStatus)
+		}
+		parts = append(parts, branch)
+	}
+	if len(task.Tags) > 0 {
+		parts = append(parts, "#"+strings.Join(task.TagNames(), " #"))
+	}
+	if len(parts) == 0 {
+		return i.GetDescription()
+	}
+	return fmt.Sprintf("%s · %s", strings.Join(parts, " "), i.GetDescription())
+}
 
 // listModel represents a list that contain some listable resource.
 type listModel struct {
@@ -26,25 +63,50 @@ type listModel struct {
 	delegateKeys *delegateKeyMap
 
 	itemType Resource
+	// archived lists hold archived resources, which can only be restored or purged.
+	archived bool
 }
 
 // newList specifies a new list model for the provided listables and resource type.
-func newList[L models.Listable](listables []L, resourceType Resource, height, width int) listModel {
-	delegateKeys := newDelegateKeyMap(resourceType)
-	x, y := appStyle.GetFrameSize()
+func newList[L models.Listable](listables []L, resourceType Resource, keys config.Keys, height, width int) listModel {
+	delegateKeys := newDelegateKeyMap(resourceType, keys)
+	x, y := frameSize()
 	m := listModel{
 		list:         list.New(itemsFromListable(listables), newDelegate(delegateKeys), width-x, height-y),
-		keys:         newListKeyMap(resourceType),
+		keys:         newListKeyMap(resourceType, keys),
 		delegateKeys: delegateKeys,
 		itemType:     resourceType,
 	}
 	m.list.AdditionalFullHelpKeys = func() []key.Binding {
 		return []key.Binding{
 			m.keys.create,
+			m.keys.archived,
+			m.keys.timesheet,
 			m.keys.toggleHelp,
 		}
 	}
 	m.list.Title = strings.Title(fmt.Sprintf("%ss", resourceType))
+	m.list.Filter = tagFilter
+	// esc navigates back, so only q should quit.
+	m.list.KeyMap.Quit.SetKeys("q")
+	return m
+}
+
+// newArchivedList specifies a list of archived resources, which can be restored or purged.
+func newArchivedList[L models.Listable](listables []L, resourceType Resource, keys config.Keys, height, width int) listModel {
+	m := newList(listables, resourceType, keys, height, width)
+	m.archived = true
+	m.list.Title = strings.Title(fmt.Sprintf("archived %ss", resourceType))
+	m.keys.create.SetEnabled(false)
+	m.keys.archived.SetEnabled(false)
+	m.keys.timesheet.SetEnabled(false)
+
+	d := m.delegateKeys
+	for _, k := range []*key.Binding{&d.choose, &d.edit, &d.start, &d.pause, &d.complete, &d.report, &d.export, &d.infer, &d.stats, &d.board} {
+		k.SetEnabled(false)
+	}
+	d.remove.SetHelp(keys.Remove[0], fmt.Sprintf("purge %s", resourceType))
+	d.restore.SetEnabled(len(listables) > 0)
 	return m
 }
 
@@ -58,36 +120,85 @@ func newDelegate(keys *delegateKeyMap) list.DefaultDelegate {
 		switch msg := msg.(type) {
 
 		case tea.KeyMsg:
+			// the actions refer to the resource by its ID, as the position in
+			// a filtered list is not its position among the resources.
+			selected, ok := m.SelectedItem().(item)
+			if !ok {
+				return nil
+			}
+			id := selected.GetID()
 			switch {
 			// Removal of resources works by having the delegate detect
 			// key stroke. It then messages to the model to delete the list
-			// item from the database. If the model succeeds, the same message will
+			// item from the database once the user has confirmed. If the model succeeds, a removedResourceMsg will
 			// propagate back to the delegate that then removes the list item from the UI.
 
 			// Detect removal of items.
 			case key.Matches(msg, keys.remove):
-				return removeResourceCmd(m.Index())
+				return removeResourceCmd(id)
 
 			case key.Matches(msg, keys.choose):
-				return chooseResourceCmd(m.Index())
+				return chooseResourceCmd(id)
+
+			case key.Matches(msg, keys.edit):
+				return editResourceCmd(id)
+
+			case key.Matches(msg, keys.start):
+				return startTaskCmd(id)
+
+			case key.Matches(msg, keys.pause):
+				return pauseTaskCmd(id)
+
+			case key.Matches(msg, keys.complete):
+				return completeTaskCmd(id)
+
+			case key.Matches(msg, keys.report):
+				return reportResourceCmd(id, false)
+
+			case key.Matches(msg, keys.restore):
+				return restoreResourceCmd(id)
+
+			case key.Matches(msg, keys.export):
+				return exportResourceCmd(id)
+
+			case key.Matches(msg, keys.infer):
+				return inferResourceCmd(id)
+
+			case key.Matches(msg, keys.stats):
+				return statsResourceCmd(id)
+
+			case key.Matches(msg, keys.board):
+				return boardResourceCmd(id)
 			}
 
-			// The message has propagated back -> we can delete the item.
-		case removeResourceMsg:
-			m.RemoveItem(msg.index)
+			// The removal has propagated back -> we can delete the item.
+		case removedResourceMsg:
+			cmd := removeItem(m, msg.id)
 			if len(m.Items()) == 0 {
 				keys.remove.SetEnabled(false)
+				keys.restore.SetEnabled(false)
 			}
+			return cmd
+
+			// Restored items move back to the list they were archived from.
+		case restoredResourceMsg:
+			cmd := removeItem(m, msg.Resource.GetID())
+			if len(m.Items()) == 0 {
+				keys.remove.SetEnabled(false)
+				keys.restore.SetEnabled(false)
+			}
+			return cmd
 		}
 		return nil
 	}
 
-	help := []key.Binding{keys.choose, keys.remove}
-	d.ShortHelpFunc = func() []key.Binding {
-		return help
+	// The bindings are read on every render, as archived lists disable some of them.
+	help := func() []key.Binding {
+		return []key.Binding{keys.choose, keys.edit, keys.remove, keys.restore, keys.start, keys.pause, keys.complete, keys.report, keys.stats, keys.board, keys.export, keys.infer}
 	}
+	d.ShortHelpFunc = help
 	d.FullHelpFunc = func() [][]key.Binding {
-		return [][]key.Binding{help}
+		return [][]key.Binding{help()}
 	}
 	return d
 }
@@ -95,30 +206,102 @@ func newDelegate(keys *delegateKeyMap) list.DefaultDelegate {
 // listKeyMap specifies which keys the list should detect.
 type listKeyMap struct {
 	create     key.Binding
+	archived   key.Binding
+	timesheet  key.Binding
 	toggleHelp key.Binding
 }
 
 // newListKeyMap returns a key map for the list.
-func newListKeyMap(resourceType Resource) *listKeyMap {
+func newListKeyMap(resourceType Resource, keys config.Keys) *listKeyMap {
 	return &listKeyMap{
-		create:     key.NewBinding(key.WithKeys("a"), key.WithHelp("a", fmt.Sprintf("add %s", resourceType))),
-		toggleHelp: key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "toggle help")),
+		create:     newBinding(keys.Add, fmt.Sprintf("add %s", resourceType)),
+		archived:   newBinding(keys.Archived, fmt.Sprintf("archived %ss", resourceType)),
+		timesheet:  newBinding(keys.Timesheet, "timesheet"),
+		toggleHelp: newBinding(keys.Help, "toggle help"),
 	}
 }
 
 // delegateKeyMap specifies the keys that a delegate should detect,
 // that is events that are linked to a SINGLE list item.
 type delegateKeyMap struct {
-	choose key.Binding
-	remove key.Binding
+	choose   key.Binding
+	edit     key.Binding
+	remove   key.Binding
+	start    key.Binding
+	pause    key.Binding
+	complete key.Binding
+	report   key.Binding
+	restore  key.Binding
+	export   key.Binding
+	infer    key.Binding
+	stats    key.Binding
+	board    key.Binding
 }
 
 // newDelegateKeyMap returns a new key map for the delegate.
-func newDelegateKeyMap(resourceType Resource) *delegateKeyMap {
-	return &delegateKeyMap{
-		choose: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", fmt.Sprintf("choose %s", resourceType))),
-		remove: key.NewBinding(key.WithKeys("x", "backspace"), key.WithHelp("x", fmt.Sprintf("remove %s", resourceType))),
+func newDelegateKeyMap(resourceType Resource, bindings config.Keys) *delegateKeyMap {
+	keys := &delegateKeyMap{
+		choose:   newBinding(bindings.Choose, fmt.Sprintf("choose %s", resourceType)),
+		edit:     newBinding(bindings.Edit, fmt.Sprintf("edit %s", resourceType)),
+		remove:   newBinding(bindings.Remove, fmt.Sprintf("archive %s", resourceType)),
+		start:    newBinding(bindings.Start, "start/resume timer"),
+		pause:    newBinding(bindings.Pause, "pause timer"),
+		complete: newBinding(bindings.Complete, "complete task"),
+		report:   newBinding(bindings.Report, "estimate report"),
+		restore:  newBinding(bindings.Restore, fmt.Sprintf("restore %s", resourceType)),
+		export:   newBinding(bindings.Export, fmt.Sprintf("export %s", resourceType)),
+		infer:    newBinding(bindings.Infer, "infer sessions from commits"),
+		stats:    newBinding(bindings.Stats, "statistics"),
+		board:    newBinding(bindings.Board, "board"),
+	}
+	// Only archived resources can be restored.
+	keys.restore.SetEnabled(false)
+	// Timers only make sense for tasks.
+	if resourceType != Task {
+		keys.start.SetEnabled(false)
+		keys.pause.SetEnabled(false)
+		keys.complete.SetEnabled(false)
+	}
+	if resourceType != Workspace {
+		keys.report.SetEnabled(false)
 	}
+	// Exports are filtered by workspace and repo.
+	if resourceType == Task {
+		keys.export.SetEnabled(false)
+	}
+	if resourceType == Task {
+		keys.stats.SetEnabled(false)
+		keys.board.SetEnabled(false)
+	}
+	// Sessions are inferred from the commits of a repo.
+	if resourceType != Repo {
+		keys.infer.SetEnabled(false)
+	}
+	return keys
+}
+
+// arrows are shown in the help instead of the names of the arrow keys.
+var arrows = map[string]string{"up": "↑", "down": "↓", "left": "←", "right": "→"}
+
+// newBinding creates a key binding whose help shows the first of the keys.
+func newBinding(keys []string, help string) key.Binding {
+	name := keys[0]
+	if arrow, ok := arrows[name]; ok {
+		name = arrow
+	}
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(name, help))
+}
+
+// setSize fits the list in the window.
+func (m *listModel) setSize(height, width int) {
+	x, y := frameSize()
+	m.list.SetSize(width-x, height-y)
+}
+
+// insert appends a resource to the list.
+func (m *listModel) insert(resource models.Listable) tea.Cmd {
+	m.delegateKeys.remove.SetEnabled(true)
+	return m.list.InsertItem(-1, item{Listable: resource})
 }
 
 // update updates the list.
@@ -127,8 +310,7 @@ func (m listModel) update(msg tea.Msg) (listModel, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tea.WindowSizeMsg:
-		x, y := appStyle.GetFrameSize()
-		m.list.SetSize(msg.Width-x, msg.Height-y)
+		m.setSize(msg.Height, msg.Width)
 
 	case tea.KeyMsg:
 		if m.list.FilterState() == list.Filtering {
@@ -138,13 +320,36 @@ func (m listModel) update(msg tea.Msg) (listModel, tea.Cmd) {
 		case key.Matches(msg, m.keys.create):
 			cmds = append(cmds, createResourceCmd())
 
+		case key.Matches(msg, m.keys.archived):
+			cmds = append(cmds, showArchivedCmd())
+
+		case key.Matches(msg, m.keys.timesheet):
+			cmds = append(cmds, showTimesheetCmd())
+
 		case key.Matches(msg, m.keys.toggleHelp):
 			m.list.SetShowHelp(!m.list.ShowHelp())
 		}
 
 	case addResourceMsg:
-		m.delegateKeys.remove.SetEnabled(true)
-		cmds = append(cmds, m.list.InsertItem(-1, item{msg.Resource}))
+		cmds = append(cmds, m.insert(msg.Resource))
+
+	case updateTaskMsg:
+		i, ok := itemIndex(&m.list, msg.Task.ID)
+		if !ok {
+			break
+		}
+		updated := item{Listable: msg.Task}
+		// the status is only known for the branch that was listed.
+		prev := m.list.Items()[i].(item)
+		if task, ok := prev.Listable.(models.Task); ok && task.Branch == msg.Task.Branch {
+			updated.branchStatus = prev.branchStatus
+		}
+		cmds = append(cmds, m.list.SetItem(i, updated))
+
+	case updatedResourceMsg:
+		if i, ok := itemIndex(&m.list, msg.Resource.GetID()); ok {
+			cmds = append(cmds, m.list.SetItem(i, item{Listable: msg.Resource}))
+		}
 	}
 
 	newList, cmd := m.list.Update(msg)
@@ -153,6 +358,30 @@ func (m listModel) update(msg tea.Msg) (listModel, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// setItems replaces the resources of the list, keeping the cursor where it was
+// if it still fits.
+func setItems[L models.Listable](m *listModel, listables []L) tea.Cmd {
+	index := m.list.Index()
+	cmd := m.list.SetItems(itemsFromListable(listables))
+	if n := len(m.list.Items()); index >= n && n > 0 {
+		m.list.Select(n - 1)
+	}
+	m.delegateKeys.remove.SetEnabled(len(listables) > 0)
+	return cmd
+}
+
+// setBranchStatuses shows the status of the branch of every task in the list,
+// keyed by the ID of the task.
+func (m *listModel) setBranchStatuses(statuses map[uint]string) {
+	for i, it := range m.list.Items() {
+		it := it.(item)
+		if task, ok := it.Listable.(models.Task); ok {
+			it.branchStatus = statuses[task.ID]
+			m.list.SetItem(i, it)
+		}
+	}
+}
+
 // view returns the view for the list.
 func (l listModel) view() string {
 	return l.list.View()
@@ -160,11 +389,96 @@ func (l listModel) view() string {
 
 // Helpers
 
+// tagFilter matches the names of the items like the default filter does,
+// while every tag:name in the term only keeps the tasks tagged exactly name,
+// as `chrono ls tasks -tag` does.
+func tagFilter(term string, targets []string) []list.Rank {
+	var tags, words []string
+	for _, word := range strings.Fields(term) {
+		if strings.HasPrefix(word, "tag:") {
+			// the name of the tag may not have been typed yet.
+			if name := strings.ToLower(strings.TrimPrefix(word, "tag:")); name != "" {
+				tags = append(tags, name)
+			}
+			continue
+		}
+		words = append(words, word)
+	}
+
+	// the matches of the names are mapped back to the targets they are from.
+	var names []string
+	var indexes []int
+	for i, target := range targets {
+		name, tagged, _ := strings.Cut(target, tagSeparator)
+		if hasTags(strings.Fields(tagged), tags) {
+			names = append(names, name)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(words) == 0 {
+		ranks := make([]list.Rank, len(names))
+		for i := range names {
+			ranks[i] = list.Rank{Index: indexes[i]}
+		}
+		return ranks
+	}
+	ranks := list.DefaultFilter(strings.Join(words, " "), names)
+	for i := range ranks {
+		ranks[i].Index = indexes[ranks[i].Index]
+	}
+	return ranks
+}
+
+// hasTags reports whether every one of wanted is among tags.
+func hasTags(tags, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, t := range tags {
+			if t == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// itemIndex returns the position of the resource with id among all the items
+// of the list, whether they are filtered out or not.
+func itemIndex(m *list.Model, id uint) (int, bool) {
+	for i, it := range m.Items() {
+		if it.(item).GetID() == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// removeItem removes the resource with id from the list. A filtered list is
+// filtered again, as the matches are not in the order of the items.
+func removeItem(m *list.Model, id uint) tea.Cmd {
+	i, ok := itemIndex(m, id)
+	if !ok {
+		return nil
+	}
+	// the filter is cleared once nothing matches it, as RemoveItem does.
+	if m.FilterState() == list.Unfiltered || len(m.VisibleItems()) == 1 {
+		m.ResetFilter()
+		m.RemoveItem(i)
+		return nil
+	}
+	items := append([]list.Item{}, m.Items()[:i]...)
+	return m.SetItems(append(items, m.Items()[i+1:]...))
+}
+
 // itemsFromListable loops over a slice of listables and converts them to a slice of []list.Item
 func itemsFromListable[L models.Listable](listable []L) []list.Item {
 	l := make([]list.Item, len(listable))
 	for i, x := range listable {
-		l[i] = item{x}
+		l[i] = item{Listable: x}
 	}
 	return l
 }
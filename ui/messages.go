@@ -1,20 +1,58 @@
 package ui
 
 import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/export"
+	"github.com/mellonnen/chronograph/git"
 	"github.com/mellonnen/chronograph/models"
-	"gorm.io/gorm"
+	"github.com/mellonnen/chronograph/report"
+	"github.com/mellonnen/chronograph/store"
+	"github.com/mellonnen/chronograph/timesheet"
+	"github.com/mellonnen/chronograph/tracker"
 )
 
 type errorMsg error
 
-type dbMsg struct {
-	DB *gorm.DB
+type storeMsg struct {
+	Store store.Store
 }
 
 type listWorkspacesMsg struct {
 	Workspaces []models.Workspace
 }
 
+type listReposMsg struct {
+	Repos []models.Repo
+}
+
+type listTasksMsg struct {
+	Tasks []models.Task
+	// BranchStatuses holds the status of the branch of each task by ID.
+	BranchStatuses map[uint]string
+}
+
+// revisionMsg carries the revision of the store, which tells when another
+// process changed something.
+type revisionMsg struct {
+	Revision int64
+	err      error
+}
+
+// reloadedMsg holds the workspaces, along with the repos of the workspace and
+// the tasks of the repo with the IDs, if they are set.
+type reloadedMsg struct {
+	Workspaces []models.Workspace
+
+	WorkspaceID uint
+	Repos       []models.Repo
+
+	RepoID         uint
+	Tasks          []models.Task
+	BranchStatuses map[uint]string
+}
+
 type addWorkspaceMsg struct {
 	Workspace models.Workspace
 }
@@ -33,9 +71,267 @@ type addResourceMsg struct {
 }
 
 type removeResourceMsg struct {
-	index int
+	id uint
+}
+
+// confirmRemoveMsg asks the user to confirm the removal of a resource and its children.
+type confirmRemoveMsg struct {
+	Resource models.Listable
+	Children tracker.Children
+}
+
+// answerMsg is sent when a confirmation is answered, then runs afterwards.
+type answerMsg struct {
+	then tea.Cmd
+}
+
+type removedResourceMsg struct {
+	id uint
+}
+
+// showArchivedMsg opens the archived resources of the current list.
+type showArchivedMsg struct{}
+
+type listArchivedMsg struct {
+	Type      Resource
+	Resources []models.Listable
+}
+
+type restoreResourceMsg struct {
+	id uint
+}
+
+type restoredResourceMsg struct {
+	Resource models.Listable
+}
+
+type editResourceMsg struct {
+	id uint
+}
+
+// saveResourceMsg is sent by the form when an edited resource should be persisted.
+type saveResourceMsg struct {
+	Resource models.Listable
+}
+
+type updatedResourceMsg struct {
+	Resource models.Listable
 }
 
 type chooseResourceMsg struct {
+	id uint
+}
+
+type startTaskMsg struct {
+	id uint
+	// branchChecked is set once the task's branch has been offered.
+	branchChecked bool
+}
+
+// offerBranchMsg is sent when a task is started on another branch than its own.
+type offerBranchMsg struct {
+	id      uint
+	Branch  string
+	Current string
+	Exists  bool
+}
+
+type startPomodoroMsg struct {
+	id uint
+}
+
+// pomodoroTickMsg is sent every second while there are pomodoros going on.
+type pomodoroTickMsg struct {
+	Time time.Time
+}
+
+// finishedPomodoroMsg is sent when the pomodoro that was due has been closed,
+// or closing it failed with err.
+type finishedPomodoroMsg struct {
+	Task models.Task
+	err  error
+}
+
+type pauseTaskMsg struct {
+	id uint
+}
+
+type annotateTaskMsg struct {
+	id   uint
+	note string
+}
+
+type completeTaskMsg struct {
+	id uint
+}
+
+type updateTaskMsg struct {
+	Task models.Task
+}
+
+// startedTaskMsg is sent when a task was started, along with the tasks that
+// were paused for it.
+type startedTaskMsg struct {
+	Task   models.Task
+	Paused []models.Task
+}
+
+// tickMsg is sent every second by an overview's ticker, the id identifies
+// which overview the ticker belongs to.
+type tickMsg struct {
+	id   int
+	Time time.Time
+}
+
+// idleTickMsg is sent periodically to check whether the user went idle.
+type idleTickMsg struct{}
+
+// idleMsg is sent when nothing happened since Since while Task was running.
+type idleMsg struct {
+	Task  models.Task
+	Repo  models.Repo
+	Since time.Time
+}
+
+// trimmedIdleMsg is sent once the idle time of a running task was dealt with.
+type trimmedIdleMsg struct {
+	Task models.Task
+}
+
+type reportResourceMsg struct {
+	id       uint
+	archived bool
+}
+
+type reportMsg struct {
+	id       uint
+	archived bool
+	Title    string
+	Report   report.Report
+}
+
+type exportResourceMsg struct {
+	id uint
+}
+
+// saveExportMsg is sent by the export form once the export is configured.
+type saveExportMsg struct {
+	Path   string
+	Format export.Format
+	Filter export.Filter
+}
+
+type exportedMsg struct {
+	Path string
+}
+
+type inferResourceMsg struct {
+	id uint
+}
+
+// inferRangeMsg is sent by the infer form with the dates to infer sessions for.
+type inferRangeMsg struct {
+	id       uint
+	From, To time.Time
+}
+
+type inferredMsg struct {
+	Repo      models.Repo
+	From, To  time.Time
+	Proposals []tracker.Proposal
+	// Tasks are the tasks of the repo that sessions can be moved to.
+	Tasks []models.Task
+}
+
+type acceptProposalMsg struct {
+	index int
+}
+
+type acceptedProposalMsg struct {
 	index int
+	Task  models.Task
+}
+
+type editProposalMsg struct {
+	index int
+}
+
+// saveProposalMsg is sent by the session form once an inferred session is adjusted.
+type saveProposalMsg struct {
+	index      int
+	Task       string
+	Start, End time.Time
+}
+
+type statsResourceMsg struct {
+	id uint
+}
+
+// statsMsg carries the workspace to draw statistics of, with only the repo in
+// question when Repo is set.
+type statsMsg struct {
+	Title     string
+	Workspace models.Workspace
+	Repo      bool
+}
+
+type boardResourceMsg struct {
+	id uint
+}
+
+// boardMsg carries the workspace to show the board of, with only the repo
+// named Repo unless it is empty.
+type boardMsg struct {
+	Title     string
+	Workspace models.Workspace
+	Repo      string
+}
+
+// moveTaskMsg is sent by the board to move a task to the column To.
+type moveTaskMsg struct {
+	Task models.Task
+	Repo models.Repo
+	To   string
+}
+
+type movedTaskMsg struct {
+	Task models.Task
+}
+
+type showTimesheetMsg struct{}
+
+// loadTimesheetMsg asks for the timesheet of the week or the day starting at From.
+type loadTimesheetMsg struct {
+	From time.Time
+	Week bool
+}
+
+type timesheetMsg struct {
+	Sheet timesheet.Sheet
+	Week  bool
+}
+
+// logTimeMsg is sent when time is to be logged on a task from the timesheet.
+type logTimeMsg struct {
+	Task models.Task
+	Day  time.Time
+}
+
+// saveEntryMsg is sent by the entry form with the time to log.
+type saveEntryMsg struct {
+	Start, End time.Time
+	Note       string
+}
+
+type loggedTimeMsg struct {
+	Task models.Task
+}
+
+type backMsg struct{}
+
+type commitsMsg struct {
+	id      uint
+	Commits []git.Commit
+	Stat    git.ShortStat
+	err     error
 }
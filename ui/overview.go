@@ -1,25 +1,177 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/git"
 	"github.com/mellonnen/chronograph/models"
 )
 
 const timeFmt = "2006-01-02 15:04:05"
 
+// lastOverviewID is used to give every overview a unique ticker id, so that
+// tickers of overviews that are no longer shown die out.
+var lastOverviewID int
+
 type overviewModel struct {
+	id   int
 	task models.Task
+	now  time.Time
+
+	keys overviewKeyMap
+	help help.Model
+
+	commits    []git.Commit
+	stat       git.ShortStat
+	commitsErr error
+
+	// note is used to annotate the latest session of the task.
+	note    textinput.Model
+	editing bool
+
+	// columns are the states of the board, to tell which one the task is in.
+	columns  []string
+	pomodoro config.Pomodoro
+}
+
+type overviewKeyMap struct {
+	start    key.Binding
+	pause    key.Binding
+	complete key.Binding
+	annotate key.Binding
+	pomodoro key.Binding
+	save     key.Binding
+	cancel   key.Binding
+}
+
+func newOverviewKeyMap(keys config.Keys) overviewKeyMap {
+	return overviewKeyMap{
+		start:    newBinding(keys.Start, "start/resume timer"),
+		pause:    newBinding(keys.Pause, "pause timer"),
+		complete: newBinding(keys.Complete, "complete task"),
+		annotate: newBinding(keys.Annotate, "annotate session"),
+		pomodoro: newBinding(keys.Pomodoro, "start pomodoro"),
+		save:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save note")),
+		cancel:   key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+	}
 }
 
-func newOverwiew(task models.Task) overviewModel {
-	return overviewModel{
+func (k overviewKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.start, k.pause, k.complete, k.annotate, k.pomodoro, k.save, k.cancel}
+}
+
+func (k overviewKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newOverwiew creates an overview of a task in the current repo.
+func newOverwiew(task models.Task, cfg config.Config) overviewModel {
+	lastOverviewID++
+	m := overviewModel{
+		id:   lastOverviewID,
 		task: task,
+		now:  time.Now(),
+		keys: newOverviewKeyMap(cfg.Keys),
+		help: help.New(),
+		note: createTextInput("Note"),
+
+		columns:  cfg.Columns,
+		pomodoro: cfg.Pomodoro,
 	}
+	m.setEditing(false)
+	return m
 }
 
-func (m overviewModel) view() string {
+// setEditing toggles between editing the session note and controlling the timer.
+func (m *overviewModel) setEditing(editing bool) tea.Cmd {
+	m.editing = editing
+	m.keys.start.SetEnabled(!editing)
+	m.keys.pause.SetEnabled(!editing)
+	m.keys.complete.SetEnabled(!editing)
+	m.keys.annotate.SetEnabled(!editing && len(m.task.TimeEntries) > 0)
+	m.keys.pomodoro.SetEnabled(!editing)
+	m.keys.save.SetEnabled(editing)
+	m.keys.cancel.SetEnabled(editing)
+	if !editing {
+		m.note.Blur()
+		return nil
+	}
+	entries := m.task.TimeEntries
+	m.note.SetValue(entries[len(entries)-1].Note.String)
+	m.note.CursorEnd()
+	return m.note.Focus()
+}
+
+// setTask shows the task as it was reloaded.
+func (m *overviewModel) setTask(task models.Task) {
+	m.task = task
+	m.keys.annotate.SetEnabled(!m.editing && len(m.task.TimeEntries) > 0)
+}
+
+// init starts the ticker that drives the elapsed time counter.
+func (m overviewModel) init() tea.Cmd {
+	return tickCmd(m.id)
+}
+
+func (m overviewModel) update(msg tea.Msg) (overviewModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tickMsg:
+		if msg.id != m.id {
+			return m, nil
+		}
+		m.now = msg.Time
+		return m, tickCmd(m.id)
+
+	case updateTaskMsg:
+		if msg.Task.ID == m.task.ID {
+			m.setTask(msg.Task)
+		}
+
+	case commitsMsg:
+		if msg.id == m.task.ID {
+			m.commits, m.stat, m.commitsErr = msg.Commits, msg.Stat, msg.err
+		}
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.start):
+			return m, startTaskCmd(m.task.ID)
+		case key.Matches(msg, m.keys.pause):
+			return m, pauseTaskCmd(m.task.ID)
+		case key.Matches(msg, m.keys.pomodoro):
+			return m, startPomodoroCmd(m.task.ID)
+		case key.Matches(msg, m.keys.complete):
+			return m, completeTaskCmd(m.task.ID)
+		case key.Matches(msg, m.keys.annotate):
+			return m, m.setEditing(true)
+		case key.Matches(msg, m.keys.save):
+			note := m.note.Value()
+			m.setEditing(false)
+			return m, annotateTaskCmd(m.task.ID, note)
+		case key.Matches(msg, m.keys.cancel):
+			m.setEditing(false)
+			return m, nil
+		}
+	}
+
+	if m.editing {
+		var cmd tea.Cmd
+		m.note, cmd = m.note.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// view renders the overview, with the break between the pomodoros of p if
+// they are of the task.
+func (m overviewModel) view(p pomodoroState) string {
 	var b strings.Builder
 	b.WriteString(titleStyle.Render(m.task.Name))
 	b.WriteString("\n\n")
@@ -31,19 +183,43 @@ func (m overviewModel) view() string {
 	}
 
 	b.WriteString(primaryStyle.Render("Status: "))
-	var status string
-	if m.task.CompletedAt.Valid {
-		status = "Complete"
-	} else {
-		status = "Incomplete"
-	}
-	b.WriteString(secondaryStyle.Render(status))
+	b.WriteString(secondaryStyle.Render(strings.Title(m.task.Status())))
+	b.WriteString("\n\n")
+
+	b.WriteString(primaryStyle.Render("State: "))
+	b.WriteString(secondaryStyle.Render(strings.Title(m.task.StateIn(m.columns))))
 	b.WriteString("\n\n")
 
 	b.WriteString(primaryStyle.Render("Estimated time: "))
-	b.WriteString(secondaryStyle.Render(shortDur(m.task.ExpectedDuration)))
+	b.WriteString(secondaryStyle.Render(models.ShortDuration(m.task.ExpectedDuration)))
 	b.WriteString("\n\n")
 
+	if m.task.StartedAt.Valid {
+		b.WriteString(primaryStyle.Render("Tracked time: "))
+		b.WriteString(secondaryStyle.Render(models.ShortDuration(m.task.Tracked(m.now).Truncate(time.Second))))
+		b.WriteString("\n\n")
+
+		if line := m.pomodoroView(p); line != "" {
+			b.WriteString(line)
+			b.WriteString("\n\n")
+		}
+
+		b.WriteString(primaryStyle.Render("Started: "))
+		b.WriteString(secondaryStyle.Render(m.task.StartedAt.Time.Format(timeFmt)))
+		b.WriteString("\n\n")
+	}
+
+	if m.task.CompletedAt.Valid {
+		b.WriteString(primaryStyle.Render("Completed: "))
+		b.WriteString(secondaryStyle.Render(m.task.CompletedAt.Time.Format(timeFmt)))
+		b.WriteString("\n\n")
+
+		cycle, _ := m.task.CycleTime()
+		b.WriteString(primaryStyle.Render("Cycle time: "))
+		b.WriteString(secondaryStyle.Render(models.ShortDuration(cycle.Truncate(time.Minute))))
+		b.WriteString("\n\n")
+	}
+
 	b.WriteString(primaryStyle.Render("Created: "))
 	b.WriteString(secondaryStyle.Render(m.task.CreatedAt.Format(timeFmt)))
 	b.WriteString("\n\n")
@@ -52,16 +228,95 @@ func (m overviewModel) view() string {
 	b.WriteString(secondaryStyle.Render(m.task.UpdatedAt.Format(timeFmt)))
 	b.WriteString("\n\n")
 
+	if len(m.task.TimeEntries) > 0 {
+		b.WriteString(primaryStyle.Render("Sessions:"))
+		b.WriteString("\n")
+		for _, e := range m.task.TimeEntries {
+			b.WriteString(secondaryStyle.Render(m.sessionView(e)))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if m.task.StartedAt.Valid {
+		b.WriteString(m.commitsView())
+	}
+
+	if m.editing {
+		b.WriteString(primaryStyle.Render(m.note.View()))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString(m.help.View(m.keys))
+
 	return b.String()
 }
 
-func shortDur(d time.Duration) string {
-	s := d.String()
-	if strings.HasSuffix(s, "m0s") {
-		s = s[:len(s)-2]
+// pomodoroView renders the countdown of the running pomodoro or break, and the
+// number of pomodoros finished compared with the estimate.
+func (m overviewModel) pomodoroView(p pomodoroState) string {
+	var b strings.Builder
+	if e := m.task.ActiveEntry(); e != nil && e.Pomodoro {
+		left := e.StartedAt.Add(m.pomodoro.Work).Sub(m.now)
+		b.WriteString(primaryStyle.Render("Pomodoro: "))
+		b.WriteString(secondaryStyle.Render(fmt.Sprintf("%s left", countdown(left))))
+		b.WriteString("\n\n")
+	}
+	if p.task.ID == m.task.ID && !p.breakEnds.IsZero() {
+		b.WriteString(primaryStyle.Render("Break: "))
+		b.WriteString(secondaryStyle.Render(fmt.Sprintf("%s left, the next pomodoro starts after it", countdown(p.breakEnds.Sub(m.now)))))
+		b.WriteString("\n\n")
+	}
+	if n := m.task.Pomodoros(); n > 0 || b.Len() > 0 {
+		estimate := int((m.task.ExpectedDuration + m.pomodoro.Work - 1) / m.pomodoro.Work)
+		b.WriteString(primaryStyle.Render("Pomodoros: "))
+		b.WriteString(secondaryStyle.Render(fmt.Sprintf("%d finished, %d estimated", n, estimate)))
+		b.WriteString("\n\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n\n")
+}
+
+// countdown formats the time left as minutes and seconds.
+func countdown(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// commitsView renders the commits made while working on the task.
+func (m overviewModel) commitsView() string {
+	var b strings.Builder
+	if m.commitsErr != nil {
+		b.WriteString(primaryStyle.Render("Commits: "))
+		b.WriteString(secondaryStyle.Render(fmt.Sprintf("could not be listed: %v", m.commitsErr)))
+		b.WriteString("\n\n")
+		return b.String()
+	}
+	b.WriteString(primaryStyle.Render(fmt.Sprintf("Commits: %d", len(m.commits))))
+	b.WriteString(secondaryStyle.Render(fmt.Sprintf("%d files changed, +%d -%d", m.stat.FilesChanged, m.stat.Insertions, m.stat.Deletions)))
+	b.WriteString("\n")
+	for _, c := range m.commits {
+		b.WriteString(secondaryStyle.Render(fmt.Sprintf("%s %s", c.ShortSHA(), c.Subject)))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// sessionView renders a single time entry as one line.
+func (m overviewModel) sessionView(e models.TimeEntry) string {
+	end := "now"
+	if e.EndedAt.Valid {
+		end = e.EndedAt.Time.Format(timeFmt)
+	}
+	line := fmt.Sprintf("%s → %s (%s)", e.StartedAt.Format(timeFmt), end, models.ShortDuration(e.Duration(m.now).Truncate(time.Second)))
+	if e.Pomodoro {
+		line += " pomodoro"
 	}
-	if strings.HasSuffix(s, "h0m") {
-		s = s[:len(s)-2]
+	if e.Note.Valid {
+		line += ": " + e.Note.String
 	}
-	return s
+	return line
 }
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/models"
+)
+
+// pomodoroState follows the pomodoros of a task, which go on while other
+// screens are shown.
+type pomodoroState struct {
+	// task is the task the pomodoros are of, it has no ID while there are none.
+	task     models.Task
+	repoPath string
+	// breakEnds is set while there is a break between pomodoros.
+	breakEnds time.Time
+	// finished counts the pomodoros finished in a row, which decides when
+	// the break is long.
+	finished int
+	// finishing is set while the pomodoro that is due is being closed.
+	finishing bool
+	// ticking is set while a tick is on its way.
+	ticking bool
+}
+
+// active reports whether a pomodoro or a break is going on.
+func (p pomodoroState) active() bool {
+	if e := p.task.ActiveEntry(); e != nil && e.Pomodoro {
+		return true
+	}
+	return p.finishing || !p.breakEnds.IsZero()
+}
+
+// tickPomodoro starts the ticker of the pomodoros if they are going on and it
+// is not already running.
+func (m *model) tickPomodoro() tea.Cmd {
+	if m.pomodoro.ticking || !m.pomodoro.active() {
+		return nil
+	}
+	m.pomodoro.ticking = true
+	return pomodoroTickCmd()
+}
+
+// pomodoroTick finishes the pomodoro that is due and starts the next one
+// after the break.
+func (m *model) pomodoroTick(now time.Time) []tea.Cmd {
+	p := &m.pomodoro
+	p.ticking = false
+	var cmds []tea.Cmd
+	if e := p.task.ActiveEntry(); e != nil && e.Pomodoro && !p.finishing {
+		if due := e.StartedAt.Add(m.cfg.Pomodoro.Work); !now.Before(due) {
+			p.finishing = true
+			cmds = append(cmds, finishPomodoroTimerCmd(m.store, p.task.ID, p.repoPath, due))
+		}
+	}
+	if !p.breakEnds.IsZero() && !now.Before(p.breakEnds) {
+		p.breakEnds = time.Time{}
+		cmds = append(cmds, startPomodoroTimerCmd(m.store, p.task.ID, p.repoPath))
+	}
+	return append(cmds, m.tickPomodoro())
+}
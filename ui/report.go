@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/report"
+)
+
+// reportModel shows the estimate-vs-actual accuracy report of a workspace.
+type reportModel struct {
+	// id is the ID of the workspace the report is of.
+	id       uint
+	archived bool
+	title    string
+	report   report.Report
+	viewport viewport.Model
+
+	keys reportKeyMap
+	help help.Model
+}
+
+type reportKeyMap struct {
+	archived key.Binding
+	back     key.Binding
+}
+
+func (k reportKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.archived, k.back}
+}
+
+func (k reportKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+func newReport(id uint, archived bool, title string, r report.Report, keys config.Keys, height, width int) reportModel {
+	toggle := "include archived"
+	if archived {
+		toggle = "exclude archived"
+	}
+	m := reportModel{
+		id:       id,
+		archived: archived,
+		title:    title,
+		report:   r,
+		keys: reportKeyMap{
+			archived: newBinding(keys.Archived, toggle),
+			back:     newBinding(keys.Back, "back"),
+		},
+		help: help.New(),
+	}
+	m.viewport = viewport.New(0, 0)
+	m.setSize(height, width)
+	m.viewport.SetContent(m.content())
+	return m
+}
+
+func (m *reportModel) setSize(height, width int) {
+	x, y := frameSize()
+	// leave room for the title and the help.
+	m.viewport.Width = width - x
+	m.viewport.Height = height - y - 4
+}
+
+func (m reportModel) update(msg tea.Msg) (reportModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.setSize(msg.Height, msg.Width)
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.archived):
+			return m, reportResourceCmd(m.id, !m.archived)
+		case key.Matches(msg, m.keys.back):
+			return m, backCmd()
+		}
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m reportModel) view() string {
+	return fmt.Sprintf("%s\n\n%s\n\n%s", titleStyle.Render(m.title), m.viewport.View(), m.help.View(m.keys))
+}
+
+// content renders the full report, which is then scrolled by the viewport.
+func (m reportModel) content() string {
+	var b strings.Builder
+
+	b.WriteString(primaryStyle.Render("Tasks"))
+	b.WriteString("\n\n")
+	if len(m.report.Rows) == 0 {
+		b.WriteString(secondaryStyle.Render("No tasks have been started yet"))
+		b.WriteString("\n")
+	} else {
+		rows := [][]string{{"Task", "Repo", "Estimate", "Actual", "Delta", "Ratio", "Pomodoros", "Cycle time"}}
+		for _, r := range m.report.Rows {
+			task := r.Task
+			if !r.Complete {
+				task += " (in progress)"
+			}
+			if r.Archived {
+				task += " (archived)"
+			}
+			rows = append(rows, []string{
+				task,
+				r.Repo,
+				models.ShortDuration(r.Estimate),
+				models.ShortDuration(r.Actual.Truncate(time.Minute)),
+				models.SignedDuration(r.Delta().Truncate(time.Minute)),
+				fmt.Sprintf("%.2f", r.Ratio()),
+				fmt.Sprint(r.Pomodoros),
+				cycleTime(r),
+			})
+		}
+		b.WriteString(table(rows))
+	}
+	b.WriteString("\n")
+
+	b.WriteString(primaryStyle.Render("Completed tasks"))
+	b.WriteString("\n\n")
+	rows := [][]string{{"", "Tasks", "Estimate", "Actual", "Ratio", "Mean overrun", "Within estimate"}}
+	for _, a := range append(m.report.Repos, m.report.Total) {
+		rows = append(rows, []string{
+			a.Name,
+			fmt.Sprint(a.Tasks),
+			models.ShortDuration(a.Estimate),
+			models.ShortDuration(a.Actual.Truncate(time.Minute)),
+			fmt.Sprintf("%.2f", a.Ratio()),
+			models.SignedDuration(a.MeanOverrun().Truncate(time.Minute)),
+			fmt.Sprintf("%.0f%%", a.WithinShare()*100),
+		})
+	}
+	b.WriteString(table(rows))
+
+	if len(m.report.Tags) > 0 {
+		b.WriteString("\n")
+		b.WriteString(primaryStyle.Render("Time by tag"))
+		b.WriteString("\n\n")
+		rows = [][]string{{"Tag", "Tasks", "Actual"}}
+		for _, t := range m.report.Tags {
+			rows = append(rows, []string{t.Name, fmt.Sprint(t.Tasks), models.ShortDuration(t.Actual.Truncate(time.Minute))})
+		}
+		b.WriteString(table(rows))
+	}
+
+	return b.String()
+}
+
+// cycleTime formats the cycle time of a row, which only completed tasks have.
+func cycleTime(r report.Row) string {
+	if !r.Complete {
+		return "-"
+	}
+	return models.ShortDuration(r.CycleTime.Truncate(time.Minute))
+}
+
+// table renders rows as left aligned columns, the first row is used as header.
+func table(rows [][]string) string {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if l := len([]rune(cell)); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = fmt.Sprintf("%-*s", widths[j], cell)
+		}
+		line := strings.Join(cells, "  ")
+		if i == 0 {
+			b.WriteString(primaryStyle.Render(line))
+		} else {
+			b.WriteString(secondaryStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
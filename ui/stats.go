@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/stats"
+)
+
+// statsModel draws charts of how the time of a workspace or a repo is spent.
+type statsModel struct {
+	title     string
+	workspace models.Workspace
+	// repo is set when the statistics are of the single repo of the workspace.
+	repo     bool
+	weeks    int
+	stats    stats.Stats
+	viewport viewport.Model
+
+	keys statsKeyMap
+	help help.Model
+}
+
+type statsKeyMap struct {
+	more  key.Binding
+	fewer key.Binding
+	back  key.Binding
+}
+
+func (k statsKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.more, k.fewer, k.back}
+}
+
+func (k statsKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+func newStats(msg statsMsg, cfg config.Config, height, width int) statsModel {
+	m := statsModel{
+		title:     msg.Title,
+		workspace: msg.Workspace,
+		repo:      msg.Repo,
+		weeks:     cfg.StatsWeeks,
+		keys: statsKeyMap{
+			more:  newBinding(cfg.Keys.More, "more weeks"),
+			fewer: newBinding(cfg.Keys.Fewer, "fewer weeks"),
+			back:  newBinding(cfg.Keys.Back, "back"),
+		},
+		help: help.New(),
+	}
+	m.viewport = viewport.New(0, 0)
+	m.setSize(height, width)
+	m.refresh()
+	return m
+}
+
+func (m *statsModel) setSize(height, width int) {
+	x, y := frameSize()
+	// leave room for the title and the help.
+	m.viewport.Width = width - x
+	m.viewport.Height = height - y - 4
+}
+
+// refresh computes the statistics for the current number of weeks and redraws them.
+func (m *statsModel) refresh() {
+	m.stats = stats.New(m.workspace, m.weeks, time.Now())
+	m.viewport.SetContent(m.content())
+}
+
+func (m statsModel) update(msg tea.Msg) (statsModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.setSize(msg.Height, msg.Width)
+		m.viewport.SetContent(m.content())
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.more):
+			m.weeks++
+			m.refresh()
+		case key.Matches(msg, m.keys.fewer):
+			if m.weeks > 1 {
+				m.weeks--
+				m.refresh()
+			}
+		}
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m statsModel) view() string {
+	return fmt.Sprintf("%s\n\n%s\n\n%s", titleStyle.Render(m.title), m.viewport.View(), m.help.View(m.keys))
+}
+
+// content draws all charts, which are then scrolled by the viewport.
+func (m statsModel) content() string {
+	var b strings.Builder
+	width := m.viewport.Width
+
+	if !m.repo {
+		b.WriteString(primaryStyle.Render("Hours per repo"))
+		b.WriteString("\n\n")
+		b.WriteString(durationChart(m.stats.Repos, width))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(primaryStyle.Render("Hours per task"))
+	b.WriteString("\n\n")
+	b.WriteString(durationChart(m.stats.Tasks, width))
+	b.WriteString("\n")
+
+	var total, max time.Duration
+	for _, d := range m.stats.Daily {
+		total += d
+		if d > max {
+			max = d
+		}
+	}
+	last := m.stats.From.AddDate(0, 0, len(m.stats.Daily)-1)
+	b.WriteString(primaryStyle.Render(fmt.Sprintf("Daily hours over the last %d weeks", m.weeks)))
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "  %s\n", sparkline(m.stats.Daily))
+	b.WriteString(secondaryStyle.Render(fmt.Sprintf("%s – %s, %s in total, at most %s a day",
+		m.stats.From.Format("January 2"), last.Format("January 2"), hoursValue(float64(total)), hoursValue(float64(max)))))
+	b.WriteString("\n\n")
+
+	b.WriteString(primaryStyle.Render("Estimate error of completed tasks"))
+	b.WriteString("\n\n")
+	labels := make([]string, len(m.stats.Errors))
+	values := make([]float64, len(m.stats.Errors))
+	for i, e := range m.stats.Errors {
+		labels[i] = e.Label
+		values[i] = float64(e.Count)
+	}
+	b.WriteString(barChart(labels, values, countValue, width))
+
+	return b.String()
+}
+
+// durationChart draws a bar chart of the time tracked on each bar.
+func durationChart(bars []stats.Bar, width int) string {
+	labels := make([]string, len(bars))
+	values := make([]float64, len(bars))
+	for i, bar := range bars {
+		labels[i] = bar.Label
+		values[i] = float64(bar.Value)
+	}
+	return barChart(labels, values, hoursValue, width)
+}
@@ -1,33 +1,90 @@
 package ui
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mellonnen/chronograph/config"
+)
+
+var (
+	titleStyle             lipgloss.Style
+	primaryStyle           lipgloss.Style
+	secondaryStyle         lipgloss.Style
+	primarySelectedStyle   lipgloss.Style
+	secondarySelectedStyle lipgloss.Style
+	primaryDimmedStyle     lipgloss.Style
+	secondaryDimmedStyle   lipgloss.Style
+	chartStyle             lipgloss.Style
 )
 
-var titleStyle = lipgloss.NewStyle().
-	Background(lipgloss.Color("62")).
-	Foreground(lipgloss.Color("230")).
-	Padding(0, 1)
+func init() {
+	applyTheme(config.ThemeAuto)
+}
+
+// palette resolves adaptive colors according to a theme.
+type palette string
+
+func (p palette) color(c lipgloss.AdaptiveColor) lipgloss.TerminalColor {
+	switch string(p) {
+	case config.ThemeDark:
+		return lipgloss.Color(c.Dark)
+	case config.ThemeLight:
+		return lipgloss.Color(c.Light)
+	case config.ThemePlain:
+		return lipgloss.NoColor{}
+	default:
+		return c
+	}
+}
+
+// fixed returns a color that looks the same on dark and light backgrounds.
+func (p palette) fixed(c string) lipgloss.TerminalColor {
+	return p.color(lipgloss.AdaptiveColor{Light: c, Dark: c})
+}
+
+// applyTheme (re)builds all styles of the TUI for the named theme.
+func applyTheme(theme string) {
+	p := palette(theme)
+
+	titleStyle = lipgloss.NewStyle().
+		Background(p.fixed("62")).
+		Foreground(p.fixed("230")).
+		Padding(0, 1)
+
+	primaryStyle = lipgloss.NewStyle().
+		Foreground(p.color(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})).
+		Padding(0, 0, 0, 2)
+
+	secondaryStyle = primaryStyle.Copy().
+		Foreground(p.color(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"}))
+
+	primarySelectedStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(p.color(lipgloss.AdaptiveColor{Light: "#F793FF", Dark: "#AD58B4"})).
+		Foreground(p.color(lipgloss.AdaptiveColor{Light: "#EE6FF8", Dark: "#EE6FF8"})).
+		Padding(0, 0, 0, 1)
 
-var primaryStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"}).
-	Padding(0, 0, 0, 2)
+	secondarySelectedStyle = primarySelectedStyle.Copy().
+		Foreground(p.color(lipgloss.AdaptiveColor{Light: "#F793FF", Dark: "#AD58B4"}))
 
-var secondaryStyle = primaryStyle.Copy().
-	Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+	primaryDimmedStyle = lipgloss.NewStyle().
+		Foreground(p.color(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})).
+		Padding(0, 0, 0, 2)
 
-var primarySelectedStyle = lipgloss.NewStyle().
-	Border(lipgloss.NormalBorder(), false, false, false, true).
-	BorderForeground(lipgloss.AdaptiveColor{Light: "#F793FF", Dark: "#AD58B4"}).
-	Foreground(lipgloss.AdaptiveColor{Light: "#EE6FF8", Dark: "#EE6FF8"}).
-	Padding(0, 0, 0, 1)
+	secondaryDimmedStyle = primaryDimmedStyle.Copy().
+		Foreground(p.color(lipgloss.AdaptiveColor{Light: "#C2B8C2", Dark: "#4D4D4D"}))
 
-var secondarySelectedStyle = primarySelectedStyle.Copy().
-	Foreground(lipgloss.AdaptiveColor{Light: "#F793FF", Dark: "#AD58B4"})
+	chartStyle = lipgloss.NewStyle().Foreground(p.fixed("62"))
 
-var primaryDimmedStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"}).
-	Padding(0, 0, 0, 2)
+	// Form styles.
+	focusedStyle = lipgloss.NewStyle().Foreground(p.fixed("205"))
+	blurredStyle = lipgloss.NewStyle().Foreground(p.fixed("240"))
+	cursorStyle = focusedStyle.Copy()
+	noStyle = lipgloss.NewStyle()
+	helpStyle = blurredStyle.Copy()
+	cursorModeHelpStyle = lipgloss.NewStyle().Foreground(p.fixed("244"))
 
-var secondaryDimmedStyle = primaryDimmedStyle.Copy().
-	Foreground(lipgloss.AdaptiveColor{Light: "#C2B8C2", Dark: "#4D4D4D"})
+	focusedButton = focusedStyle.Copy().Render("[ Submit ]")
+	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Submit"))
+}
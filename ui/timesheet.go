@@ -0,0 +1,276 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mellonnen/chronograph/config"
+	"github.com/mellonnen/chronograph/models"
+	"github.com/mellonnen/chronograph/timesheet"
+)
+
+// maxTaskWidth is the widest a task name gets in the timesheet before it is cut.
+const maxTaskWidth = 24
+
+// timesheetModel shows the time logged on each task per day, for a single day
+// or a whole week.
+type timesheetModel struct {
+	sheet timesheet.Sheet
+	week  bool
+	// row and col is the cell under the cursor, col is always 0 for a single day.
+	row, col int
+	height   int
+
+	keys timesheetKeyMap
+	help help.Model
+}
+
+type timesheetKeyMap struct {
+	up    key.Binding
+	down  key.Binding
+	left  key.Binding
+	right key.Binding
+	prev  key.Binding
+	next  key.Binding
+	today key.Binding
+	view  key.Binding
+	log   key.Binding
+	back  key.Binding
+}
+
+func (k timesheetKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.prev, k.next, k.today, k.view, k.log, k.back}
+}
+
+func (k timesheetKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+func newTimesheet(msg timesheetMsg, keys config.Keys, height int) timesheetModel {
+	m := timesheetModel{
+		height: height,
+		keys: timesheetKeyMap{
+			up:    newBinding(keys.Up, "up"),
+			down:  newBinding(keys.Down, "down"),
+			left:  newBinding(keys.Left, "previous day"),
+			right: newBinding(keys.Right, "next day"),
+			prev:  newBinding(keys.Previous, "previous"),
+			next:  newBinding(keys.Next, "next"),
+			today: newBinding(keys.Today, "today"),
+			view:  newBinding(keys.View, "day/week"),
+			log:   newBinding(keys.Add, "log time"),
+			back:  newBinding(keys.Back, "back"),
+		},
+		help: help.New(),
+	}
+	m.setSheet(msg)
+	// a new week starts with the cursor on today.
+	if msg.Week {
+		m.col = int(time.Since(msg.Sheet.From).Hours() / 24)
+		m.clamp()
+	}
+	return m
+}
+
+// setSheet shows another sheet, keeping the cursor where it was if it fits.
+func (m *timesheetModel) setSheet(msg timesheetMsg) {
+	m.sheet = msg.Sheet
+	m.week = msg.Week
+	m.clamp()
+}
+
+func (m *timesheetModel) clamp() {
+	if m.row >= len(m.sheet.Rows) {
+		m.row = len(m.sheet.Rows) - 1
+	}
+	if m.row < 0 {
+		m.row = 0
+	}
+	if m.col >= m.sheet.Days() {
+		m.col = m.sheet.Days() - 1
+	}
+	if m.col < 0 {
+		m.col = 0
+	}
+}
+
+// selected returns the task under the cursor and the day of the cell.
+func (m timesheetModel) selected() (models.Task, time.Time, bool) {
+	if len(m.sheet.Rows) == 0 {
+		return models.Task{}, time.Time{}, false
+	}
+	return m.sheet.Rows[m.row].Task, m.sheet.Day(m.col), true
+}
+
+// period returns the number of days shown at once.
+func (m timesheetModel) period() int {
+	if m.week {
+		return 7
+	}
+	return 1
+}
+
+func (m timesheetModel) update(msg tea.Msg) (timesheetModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		from := m.sheet.From
+		switch {
+		case key.Matches(msg, m.keys.up):
+			m.row--
+			m.clamp()
+		case key.Matches(msg, m.keys.down):
+			m.row++
+			m.clamp()
+		case key.Matches(msg, m.keys.left):
+			if !m.week {
+				return m, loadTimesheetCmd(from.AddDate(0, 0, -1), false)
+			}
+			m.col--
+			m.clamp()
+		case key.Matches(msg, m.keys.right):
+			if !m.week {
+				return m, loadTimesheetCmd(from.AddDate(0, 0, 1), false)
+			}
+			m.col++
+			m.clamp()
+		case key.Matches(msg, m.keys.prev):
+			return m, loadTimesheetCmd(from.AddDate(0, 0, -m.period()), m.week)
+		case key.Matches(msg, m.keys.next):
+			return m, loadTimesheetCmd(from.AddDate(0, 0, m.period()), m.week)
+		case key.Matches(msg, m.keys.today):
+			m.col = 0
+			if m.week {
+				m.col = (int(time.Now().Weekday()) + 6) % 7
+				return m, loadTimesheetCmd(timesheet.StartOfWeek(time.Now()), true)
+			}
+			return m, loadTimesheetCmd(time.Now(), false)
+		case key.Matches(msg, m.keys.view):
+			day := m.sheet.Day(m.col)
+			if m.week {
+				m.col = 0
+				return m, loadTimesheetCmd(day, false)
+			}
+			m.col = (int(day.Weekday()) + 6) % 7
+			return m, loadTimesheetCmd(timesheet.StartOfWeek(day), true)
+		case key.Matches(msg, m.keys.log):
+			if task, day, ok := m.selected(); ok {
+				return m, logTimeCmd(task, day)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m timesheetModel) title() string {
+	if !m.week {
+		return fmt.Sprintf("Timesheet of %s", m.sheet.From.Format("Monday, January 2, 2006"))
+	}
+	_, week := m.sheet.From.ISOWeek()
+	last := m.sheet.Day(m.sheet.Days() - 1)
+	return fmt.Sprintf("Timesheet of week %d, %s – %s", week, m.sheet.From.Format("January 2"), last.Format("January 2, 2006"))
+}
+
+func (m timesheetModel) view() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", titleStyle.Render(m.title()))
+
+	if len(m.sheet.Rows) == 0 {
+		b.WriteString(secondaryStyle.Render("No time was logged and there are no open tasks"))
+		fmt.Fprintf(&b, "\n\n%s", m.help.View(m.keys))
+		return b.String()
+	}
+
+	header := []string{"Task", "Repo"}
+	for i := 0; i < m.sheet.Days(); i++ {
+		day := m.sheet.Day(i)
+		if m.week {
+			header = append(header, day.Format("Mon 2"))
+		} else {
+			header = append(header, day.Format("Monday"))
+		}
+	}
+	header = append(header, "Total")
+
+	rows := [][]string{header}
+	for _, r := range m.sheet.Rows {
+		name := []rune(r.Task.Name)
+		if len(name) > maxTaskWidth {
+			name = append(name[:maxTaskWidth-1], '…')
+		}
+		row := []string{string(name), r.Repo}
+		for _, d := range r.Days {
+			row = append(row, hours(d))
+		}
+		rows = append(rows, append(row, hours(r.Total)))
+	}
+	totals := []string{"Total", ""}
+	for _, d := range m.sheet.Totals {
+		totals = append(totals, hours(d))
+	}
+	rows = append(rows, append(totals, hours(m.sheet.Total)))
+
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			if l := len([]rune(cell)); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+
+	// only the rows around the cursor fit, next to the title, header, totals and help.
+	_, y := frameSize()
+	visible := m.height - y - 8
+	if visible < 1 {
+		visible = 1
+	}
+	first := 0
+	if m.row >= visible {
+		first = m.row - visible + 1
+	}
+
+	plain := secondaryStyle.Copy().Padding(0)
+	bold := primaryStyle.Copy().Padding(0)
+	line := func(row []string, style lipgloss.Style, selected int) string {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cell = fmt.Sprintf("%-*s", widths[j], cell)
+			if j == selected {
+				cells[j] = focusedStyle.Render(cell)
+			} else {
+				cells[j] = style.Render(cell)
+			}
+		}
+		return "  " + strings.Join(cells, "  ") + "\n"
+	}
+
+	b.WriteString(line(header, bold, -1))
+	for i := first; i < len(m.sheet.Rows) && i < first+visible; i++ {
+		selected := -1
+		if i == m.row {
+			// the first two columns name the task.
+			selected = m.col + 2
+		}
+		b.WriteString(line(rows[i+1], plain, selected))
+	}
+	b.WriteString(line(rows[len(rows)-1], bold, -1))
+
+	fmt.Fprintf(&b, "\n%s", m.help.View(m.keys))
+	return b.String()
+}
+
+// hours formats the time logged in a cell, empty cells show a dot.
+func hours(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	if d == 0 {
+		return "·"
+	}
+	return models.ShortDuration(d)
+}
@@ -5,5 +5,6 @@ import (
 )
 
 func (m model) errorView() string {
-	return fmt.Sprintf("An error occurred, please file an issue at https://github.com/mellonnen/chronograph \n\n Error Trace:\n%s", m.err.Error())
+	return fmt.Sprintf("An error occurred, please file an issue at https://github.com/mellonnen/chronograph \n\n Error Trace:\n%s", m.err.Error()) +
+		helpStyle.Render(fmt.Sprintf("\n\n%s to go back, q to quit", m.backKey.Help().Key))
 }